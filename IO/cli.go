@@ -15,11 +15,17 @@
 package IO
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/Chris-Greaves/stencil/manifest"
 )
 
+var stdin = bufio.NewReader(os.Stdin)
+
 type CLI struct {
 }
 
@@ -28,7 +34,7 @@ func (c CLI) GetOverrides(allSettings []confighelper.Setting) ([]confighelper.Se
 	var updatedSets []confighelper.Setting
 
 	for _, setting := range allSettings {
-		if output := offerSettingToUser(setting); output != "" {
+		if output, changed := offerSettingToUser(setting); changed {
 			updatedSets = append(updatedSets, confighelper.Setting{Name: setting.Name, Value: output})
 		}
 	}
@@ -36,9 +42,111 @@ func (c CLI) GetOverrides(allSettings []confighelper.Setting) ([]confighelper.Se
 	return updatedSets, nil
 }
 
-func offerSettingToUser(setting confighelper.Setting) string {
-	fmt.Printf("Conf Override: \"%v\" [%v]: ", setting.Name, setting.Value)
-	output := ""
-	fmt.Scanln(&output)
-	return output
+// Confirm asks the user a yes or no question, defaulting to no
+func (c CLI) Confirm(message string) (bool, error) {
+	fmt.Printf("%v [y/N]: ", message)
+
+	answer, _ := readLine()
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Choose asks the user to pick one of the choices, accepting any unambiguous prefix and asking again until the answer matches.
+// A closed input picks the first choice.
+func (c CLI) Choose(message string, choices []string) (string, error) {
+	fmt.Println(message)
+
+	for {
+		fmt.Printf("%v: ", strings.Join(choices, "/"))
+
+		input, closed := readLine()
+		answer := strings.ToLower(strings.TrimSpace(input))
+		if answer == "" && closed {
+			return choices[0], nil
+		}
+
+		var matches []string
+		for _, choice := range choices {
+			if answer == choice {
+				return choice, nil
+			}
+			if answer != "" && strings.HasPrefix(choice, answer) {
+				matches = append(matches, choice)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+
+		if closed {
+			return "", fmt.Errorf("'%v' isn't one of %v", input, strings.Join(choices, ", "))
+		}
+		fmt.Printf("Please answer one of %v\n", strings.Join(choices, ", "))
+	}
+}
+
+// offerSettingToUser prompts for a new value for the setting, asking again until the input can be converted to the setting's type.
+// An empty answer keeps the current value.
+func offerSettingToUser(setting confighelper.Setting) (interface{}, bool) {
+	for {
+		fmt.Printf("Conf Override: \"%v\" [%v]: ", setting.Name, confighelper.FormatValue(setting.Value))
+
+		input, _ := readLine()
+		if input == "" {
+			return nil, false
+		}
+
+		value, err := confighelper.ParseValue(input, setting.Value)
+		if err != nil {
+			fmt.Printf("Invalid value: %v\n", err.Error())
+			continue
+		}
+		return value, true
+	}
+}
+
+// Prompt asks the user for the variable, showing its help text and choices, and asks again until the answer is valid.
+// An empty answer keeps the current value.
+func (c CLI) Prompt(variable manifest.Variable, current interface{}) (interface{}, error) {
+	if variable.Help != "" {
+		fmt.Println(variable.Help)
+	}
+
+	question := variable.Prompt()
+	if len(variable.Choices) > 0 {
+		question += " (" + variable.ChoicesText() + ")"
+	}
+
+	for {
+		fmt.Printf("%v [%v]: ", question, confighelper.FormatValue(current))
+
+		input, closed := readLine()
+		value := current
+		var err error
+		if input != "" {
+			value, err = confighelper.ParseValue(input, variable.Like(current))
+		}
+		if err == nil {
+			err = variable.Validate(value)
+		}
+		if err == nil {
+			return value, nil
+		}
+
+		if closed {
+			return nil, fmt.Errorf("Invalid value for '%v'. Error: %v", variable.Name, err.Error())
+		}
+		fmt.Printf("Invalid value: %v\n", err.Error())
+	}
+}
+
+// readLine reads a whole line from stdin, so values may contain spaces. A closed input reads as empty, accepting the remaining defaults,
+// and is reported so callers don't keep asking.
+func readLine() (string, bool) {
+	line, err := stdin.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err != nil
 }
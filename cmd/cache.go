@@ -0,0 +1,119 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Chris-Greaves/stencil/fetch"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/spf13/cobra"
+)
+
+var cacheMaxAge time.Duration
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of git templates",
+	Long: `Git templates are cloned into a local cache, so they can be reused between runs and used with --offline.
+
+The cache lives in $XDG_CACHE_HOME/stencil, or the user's cache directory if that isn't set.`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the cached git templates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := templateCache()
+		if err != nil {
+			return err
+		}
+
+		entries, err := cache.List()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("The cache is empty")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "URL\tREF\tCOMMIT\tFETCHED")
+		for _, entry := range entries {
+			ref := entry.Ref
+			if ref == "" {
+				ref = "(default)"
+			}
+			fmt.Fprintf(w, "%v\t%v\t%.12v\t%v\n", entry.URL, ref, entry.Commit, entry.FetchedAt.Format(time.RFC1123))
+		}
+		return w.Flush()
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean [url...]",
+	Short: "Remove cached git templates, either everything or only the urls given",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := templateCache()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return cache.Clean()
+		}
+		for _, url := range args {
+			if err := cache.Remove(url); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+
+	rootCmd.PersistentFlags().DurationVar(&cacheMaxAge, "cache-max-age", time.Hour, "how long a cached branch or tag is used before it is fetched again")
+}
+
+func templateCache() (fetch.Cache, error) {
+	dir, err := fetch.DefaultCacheDir()
+	if err != nil {
+		return fetch.Cache{}, err
+	}
+	return fetch.NewCache(dir, cacheMaxAge), nil
+}
+
+// templateAvailable reports whether the git template can be used, only asking the remote whether the repo exists when it isn't cached.
+// A stale cached copy is enough, as fetching it falls back to the cache when the remote can't be reached.
+func templateAvailable(url, ref string, auth transport.AuthMethod) bool {
+	if offline {
+		// Fetching explains that an uncached template has to be fetched once first
+		return true
+	}
+	cache, err := templateCache()
+	if err != nil {
+		return fetch.IsGitURL(url, auth)
+	}
+	return cache.Available(url, ref, auth, false)
+}
@@ -0,0 +1,126 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers"
+	"github.com/Chris-Greaves/stencil/fetch"
+	"github.com/Chris-Greaves/stencil/inherit"
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/pkg/errors"
+)
+
+// extendTemplate layers the templates that the template in templatePath extends underneath it, following each base's own extends.
+// root is the directory or git checkout holding the template, which local bases must be inside. Git bases are fetched at the commit
+// pinned for their source, if there is one, and the commit used for each is returned. The returned template must be cleaned up once it has been used.
+func extendTemplate(root, templatePath string, pinned map[string]string, refreshBases bool) (inherit.Template, map[string]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return inherit.Template{}, nil, err
+	}
+	templatePath, err = filepath.Abs(templatePath)
+	if err != nil {
+		return inherit.Template{}, nil, err
+	}
+
+	commits := map[string]string{}
+	template, err := layerBases(root, templatePath, pinned, refreshBases, commits, nil)
+	if err != nil {
+		return inherit.Template{}, nil, err
+	}
+	return template, commits, nil
+}
+
+// layerBases layers the base of the template in dir, inside root, underneath it after layering the base's own base. extending holds the
+// directories of the templates already being layered, so a template that ends up extending itself is an error rather than looping forever.
+func layerBases(root, dir string, pinned map[string]string, refreshBases bool, commits map[string]string, extending []string) (inherit.Template, error) {
+	templateManifest, err := manifest.Load(filepath.Join(dir, ".stencil"))
+	if err != nil {
+		return inherit.Template{}, errors.Wrap(err, "Error parsing manifest file")
+	}
+	source := templateManifest.Extends
+	if source == "" {
+		return inherit.Local(dir), nil
+	}
+
+	baseRoot, baseDir, err := fetchBase(root, dir, source, pinned, refreshBases, commits)
+	if err != nil {
+		return inherit.Template{}, errors.Wrapf(err, "Error retrieving the template it extends, '%v'", source)
+	}
+	extending = append(extending, dir)
+	for _, other := range extending {
+		if other == baseDir {
+			return inherit.Template{}, fmt.Errorf("The template extends '%v', which already extends it", source)
+		}
+	}
+	base, err := layerBases(baseRoot, baseDir, pinned, refreshBases, commits, extending)
+	if err != nil {
+		return inherit.Template{}, err
+	}
+
+	template, err := inherit.Layer(base, dir)
+	if err != nil {
+		base.Cleanup()
+		return inherit.Template{}, errors.Wrapf(err, "Error extending '%v'", source)
+	}
+	return template, nil
+}
+
+// fetchBase finds the base template a template in dir extends, returning the root it is in and its directory. Local paths are relative
+// to dir and must stay inside root, so a template, especially one from git, can't read files from elsewhere on the machine.
+// Anything else is a git url that can include a ref and subdirectory like the templates given to stencil.
+func fetchBase(root, dir, source string, pinned map[string]string, refreshBases bool, commits map[string]string) (string, string, error) {
+	if filepath.IsAbs(source) {
+		return "", "", errors.Errorf("'%v' is an absolute path, a base template must be a git url or a path relative to the template", source)
+	}
+	local := filepath.Join(dir, source)
+	if fetch.IsPath(local) || strings.HasPrefix(source, ".") {
+		if !handlers.IsWithin(local, root) {
+			return "", "", errors.Errorf("'%v' is outside '%v', a base template must be a git url or a path inside the template's directory or repository", local, root)
+		}
+		if !fetch.IsPath(local) {
+			return "", "", errors.Errorf("There is no template directory at '%v'", local)
+		}
+		return root, local, nil
+	}
+
+	url, ref := fetch.SplitRef(source)
+	url, baseSubdir := fetch.SplitSubdir(url)
+	if commit, found := pinned[source]; found {
+		ref = commit
+	}
+
+	auth, err := gitAuth(url)
+	if err != nil {
+		return "", "", err
+	}
+	cache, err := templateCache()
+	if err != nil {
+		return "", "", err
+	}
+	entry, err := cache.Fetch(url, fetch.PullOptions{Ref: ref, Shallow: shallow, Auth: auth}, offline, refreshBases)
+	if err != nil {
+		return "", "", err
+	}
+	fmt.Printf("Extending template %v at commit %v\n", url, entry.Commit)
+	commits[source] = entry.Commit
+
+	baseDir, err := fetch.ResolveSubdir(entry.Dir, baseSubdir)
+	return entry.Dir, baseDir, err
+}
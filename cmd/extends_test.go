@@ -0,0 +1,83 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtendTemplateLayersLocalBaseInsideTheRoot(t *testing.T) {
+	root := createExtendsTest(t, map[string]string{
+		"base/.stencil/.stencil.json":    `{}`,
+		"base/LICENSE":                   "MIT",
+		"service/.stencil/.stencil.json": `{}`,
+		"service/.stencil/manifest.json": `{ "extends": "../base" }`,
+		"service/main.go":                "package main",
+	})
+	defer os.RemoveAll(root)
+
+	template, bases, err := extendTemplate(root, filepath.Join(root, "service"), nil, false)
+	require.NoError(t, err)
+	defer template.Cleanup()
+
+	assert.Empty(t, bases)
+	assert.FileExists(t, filepath.Join(template.Dir, "LICENSE"))
+	assert.FileExists(t, filepath.Join(template.Dir, "main.go"))
+}
+
+func TestExtendTemplateRefusesAbsoluteBase(t *testing.T) {
+	secret := createExtendsTest(t, map[string]string{"id_rsa": "secret"})
+	defer os.RemoveAll(secret)
+	root := createExtendsTest(t, map[string]string{
+		".stencil/.stencil.json": `{}`,
+		".stencil/manifest.json": `{ "extends": "` + filepath.ToSlash(secret) + `" }`,
+	})
+	defer os.RemoveAll(root)
+
+	_, _, err := extendTemplate(root, root, nil, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is an absolute path, a base template must be a git url or a path relative to the template")
+}
+
+func TestExtendTemplateRefusesBaseOutsideTheRoot(t *testing.T) {
+	root := createExtendsTest(t, map[string]string{
+		"secret/id_rsa":                  "secret",
+		"service/.stencil/.stencil.json": `{}`,
+		"service/.stencil/manifest.json": `{ "extends": "../secret" }`,
+	})
+	defer os.RemoveAll(root)
+
+	_, _, err := extendTemplate(filepath.Join(root, "service"), filepath.Join(root, "service"), nil, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a base template must be a git url or a path inside the template's directory or repository")
+}
+
+func createExtendsTest(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "stencil-extends-test-")
+	require.NoError(t, err)
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	return dir
+}
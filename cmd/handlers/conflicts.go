@@ -0,0 +1,290 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/pkg/errors"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ConflictPolicy decides what happens when the template would change a file that already exists in the output
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite replaces existing files with the rendered template
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictSkip keeps existing files as they are
+	ConflictSkip
+	// ConflictFail stops before anything is written if any existing file would change
+	ConflictFail
+	// ConflictAsk shows a diff for each existing file and asks the user what to do with it
+	ConflictAsk
+)
+
+// The answers to a conflict when the policy is ConflictAsk
+const (
+	ChoiceKeep      = "keep"
+	ChoiceOverwrite = "overwrite"
+	ChoiceMerge     = "merge"
+	ChoiceAll       = "all"
+)
+
+const diffContext = 3
+
+// ConflictError lists the existing files that would be changed by the template
+type ConflictError struct {
+	Paths []string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("%v existing files would be overwritten:\n  %v", len(e.Paths), strings.Join(e.Paths, "\n  "))
+}
+
+// CheckConflicts returns a ConflictError if the policy is ConflictFail and processing the template would change any existing file
+func (h RootHandler) CheckConflicts(templatePath, outputPath string) error {
+	if h.Conflicts != ConflictFail {
+		return nil
+	}
+
+	plan, err := h.PlanTemplate(templatePath, outputPath)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for _, planned := range plan {
+		if planned.Status == StatusConflict {
+			conflicts = append(conflicts, planned.TargetPath)
+		}
+	}
+	if len(conflicts) > 0 {
+		return ConflictError{Paths: conflicts}
+	}
+	return nil
+}
+
+// conflictResolver applies the conflict policy to the files of a single run, remembering when the user chooses to overwrite all of them
+type conflictResolver struct {
+	overwriteAll bool
+}
+
+// resolve decides what to write to an existing target, rendering the file with h. It returns the contents to write, or nil when the
+// existing file should be kept.
+func (r *conflictResolver) resolve(h RootHandler, path, relPath, targetPath string) ([]byte, error) {
+	existing, err := ioutil.ReadFile(targetPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error reading existing file '%v'", targetPath)
+	}
+
+	rendered := new(bytes.Buffer)
+	if err = h.writeFile(path, relPath, rendered); err != nil {
+		return nil, err
+	}
+	if bytes.Equal(existing, rendered.Bytes()) {
+		fmt.Printf("Unchanged %v\n", targetPath)
+		return nil, nil
+	}
+
+	if r.overwriteAll {
+		return rendered.Bytes(), nil
+	}
+
+	switch h.Conflicts {
+	case ConflictSkip:
+		fmt.Printf("Keeping existing %v\n", targetPath)
+		return nil, nil
+	case ConflictFail:
+		return nil, ConflictError{Paths: []string{targetPath}}
+	}
+
+	message := fmt.Sprintf("%v already exists and differs from the template:\n%v", targetPath, unifiedDiff(string(existing), rendered.String()))
+	choice, err := h.IO.Choose(message, []string{ChoiceKeep, ChoiceOverwrite, ChoiceMerge, ChoiceAll})
+	if err != nil {
+		return nil, err
+	}
+
+	switch choice {
+	case ChoiceOverwrite:
+		return rendered.Bytes(), nil
+	case ChoiceMerge:
+		return mergeWithMarkers(string(existing), rendered.String()), nil
+	case ChoiceAll:
+		r.overwriteAll = true
+		return rendered.Bytes(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// diffLine is a single line of a line oriented diff
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+func diffLines(existing, rendered string) []diffLine {
+	var lines []diffLine
+	for _, d := range diff.Do(existing, rendered) {
+		for _, line := range splitLines(d.Text) {
+			lines = append(lines, diffLine{op: d.Type, text: line})
+		}
+	}
+	return lines
+}
+
+// unifiedDiff shows the changes from existing to rendered as hunks of a unified diff
+func unifiedDiff(existing, rendered string) string {
+	lines := diffLines(existing, rendered)
+
+	var sb strings.Builder
+	sb.WriteString("--- existing\n+++ template\n")
+
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffmatchpatch.DiffEqual {
+			i++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		// Extend the hunk to cover changes separated by no more than twice the context
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].op != diffmatchpatch.DiffEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == diffmatchpatch.DiffEqual {
+				next++
+			}
+			if next == len(lines) || next-end > 2*diffContext {
+				end += diffContext
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = next
+		}
+
+		hunkOld, hunkNew := oldLine-(i-start), newLine-(i-start)
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, line := range lines[start:end] {
+			switch line.op {
+			case diffmatchpatch.DiffEqual:
+				body.WriteString(" " + line.text)
+				oldCount++
+				newCount++
+			case diffmatchpatch.DiffDelete:
+				body.WriteString("-" + line.text)
+				oldCount++
+			case diffmatchpatch.DiffInsert:
+				body.WriteString("+" + line.text)
+				newCount++
+			}
+			if !strings.HasSuffix(line.text, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%v,%v +%v,%v @@\n%v", hunkOld, oldCount, hunkNew, newCount, body.String())
+
+		for _, line := range lines[i:end] {
+			if line.op != diffmatchpatch.DiffInsert {
+				oldLine++
+			}
+			if line.op != diffmatchpatch.DiffDelete {
+				newLine++
+			}
+		}
+		i = end
+	}
+
+	return sb.String()
+}
+
+// mergeWithMarkers keeps the lines the existing file and the rendered template share, and wraps each difference in conflict markers
+// so the user can resolve it in their editor
+func mergeWithMarkers(existing, rendered string) []byte {
+	var merged, ours, theirs bytes.Buffer
+	flush := func() {
+		if ours.Len() == 0 && theirs.Len() == 0 {
+			return
+		}
+		merged.WriteString("<<<<<<< existing\n")
+		writeLines(&merged, ours.String())
+		merged.WriteString("=======\n")
+		writeLines(&merged, theirs.String())
+		merged.WriteString(">>>>>>> template\n")
+		ours.Reset()
+		theirs.Reset()
+	}
+
+	for _, line := range diffLines(existing, rendered) {
+		switch line.op {
+		case diffmatchpatch.DiffDelete:
+			ours.WriteString(line.text)
+		case diffmatchpatch.DiffInsert:
+			theirs.WriteString(line.text)
+		default:
+			flush()
+			merged.WriteString(line.text)
+		}
+	}
+	flush()
+
+	return merged.Bytes()
+}
+
+// writeLines writes text, making sure it ends with a newline so a following marker starts on its own line
+func writeLines(buf *bytes.Buffer, text string) {
+	buf.WriteString(text)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		buf.WriteString("\n")
+	}
+}
+
+// splitLines splits text after each newline, keeping the newlines
+func splitLines(text string) []string {
+	var lines []string
+	for text != "" {
+		idx := strings.Index(text, "\n")
+		if idx < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:idx+1])
+		text = text[idx+1:]
+	}
+	return lines
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,124 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTemplateSkipsExistingFiles(t *testing.T) {
+	handler, templatePath, outputPath := createConflictTest(t)
+	handler.Conflicts = ConflictSkip
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+
+	assertContents(t, filepath.Join(outputPath, "edited.txt"), "local edit\n")
+	assertContents(t, filepath.Join(outputPath, "new.txt"), "new\n")
+}
+
+func TestCheckConflictsListsChangedFiles(t *testing.T) {
+	handler, templatePath, outputPath := createConflictTest(t)
+	handler.Conflicts = ConflictFail
+
+	err := handler.CheckConflicts(templatePath, outputPath)
+	require.Error(t, err)
+	assert.Equal(t, ConflictError{Paths: []string{filepath.Join(outputPath, "edited.txt")}}, err)
+
+	handler.Conflicts = ConflictOverwrite
+	assert.NoError(t, handler.CheckConflicts(templatePath, outputPath))
+}
+
+func TestProcessTemplateMergesWhenAsked(t *testing.T) {
+	handler, templatePath, outputPath := createConflictTest(t)
+	handler.Conflicts = ConflictAsk
+
+	mockIO := handler.IO.(*mocks.IOWrapper)
+	mockIO.On("Choose", mock.MatchedBy(func(message string) bool {
+		return assert.Contains(t, message, "-local edit\n+template\n")
+	}), []string{ChoiceKeep, ChoiceOverwrite, ChoiceMerge, ChoiceAll}).Return(ChoiceMerge, nil).Once()
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+	mockIO.AssertExpectations(t)
+
+	assertContents(t, filepath.Join(outputPath, "edited.txt"), "<<<<<<< existing\nlocal edit\n=======\ntemplate\n>>>>>>> template\n")
+	assertContents(t, filepath.Join(outputPath, "same.txt"), "same\n")
+}
+
+func TestProcessTemplateOverwritesTheRestWhenAskedForAll(t *testing.T) {
+	handler, templatePath, outputPath := createConflictTest(t)
+	handler.Conflicts = ConflictAsk
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outputPath, "new.txt"), []byte("also edited\n"), 0644))
+
+	mockIO := handler.IO.(*mocks.IOWrapper)
+	mockIO.On("Choose", mock.Anything, mock.Anything).Return(ChoiceAll, nil).Once()
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+	mockIO.AssertExpectations(t)
+
+	assertContents(t, filepath.Join(outputPath, "edited.txt"), "template\n")
+	assertContents(t, filepath.Join(outputPath, "new.txt"), "new\n")
+}
+
+func TestPlanTemplateReportsSkippedFiles(t *testing.T) {
+	handler, templatePath, outputPath := createConflictTest(t)
+	handler.Conflicts = ConflictSkip
+
+	plan, err := handler.PlanTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	PrintPlan(&b, "out", plan)
+	assert.Equal(t, "out\n  edited.txt (skipped)\n  new.txt (new)\n  same.txt (unchanged)\n\n1 new, 0 overwritten, 1 unchanged, 1 skipped\n", b.String())
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	existing := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	rendered := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+	assert.Equal(t, "--- existing\n+++ template\n"+
+		"@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n"+
+		"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n", unifiedDiff(existing, rendered))
+}
+
+func TestMergeWithMarkers(t *testing.T) {
+	merged := mergeWithMarkers("a\nb\nc", "a\nB\nc\nd\n")
+	assert.Equal(t, "a\n<<<<<<< existing\nb\nc\n=======\nB\nc\nd\n>>>>>>> template\n", string(merged))
+}
+
+// createConflictTest creates a template with edited.txt, same.txt and new.txt, and an output where edited.txt has local changes
+// and same.txt already matches the template
+func createConflictTest(t *testing.T) (RootHandler, string, string) {
+	handler, templatePath, outputPath := createHandlerTest(t, `{}`)
+	writeFiles(t, templatePath, map[string]string{"edited.txt": "template\n", "same.txt": "same\n", "new.txt": "new\n"})
+	writeFiles(t, outputPath, map[string]string{"edited.txt": "local edit\n", "same.txt": "same\n"})
+	return handler, templatePath, outputPath
+}
+
+func assertContents(t *testing.T, path, expected string) {
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(contents))
+}
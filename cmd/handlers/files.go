@@ -0,0 +1,100 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/pkg/errors"
+)
+
+// sniffLength is the number of bytes read from the start of a file to decide whether it is binary
+const sniffLength = 8000
+
+// writeFile writes the contents of the template file at path to wr. Binary files and files matching the manifest's copyOnly globs
+// are copied byte for byte, everything else is parsed and executed as a template.
+func (h RootHandler) writeFile(path, relPath string, wr io.Writer) error {
+	copyOnly := h.Manifest.IsCopyOnly(relPath)
+	if !copyOnly {
+		binary, err := isBinaryFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "Error reading file %v", path)
+		}
+		copyOnly = binary
+	}
+
+	if copyOnly {
+		if err := copyFile(path, wr); err != nil {
+			return errors.Wrapf(err, "Error copying file %v", path)
+		}
+		return nil
+	}
+
+	if err := h.contentEngine(relPath).ParseAndExecuteFile(path, h.Config.Object(), wr); err != nil {
+		return errors.Wrapf(err, "Error processing file %v", path)
+	}
+	return nil
+}
+
+// contentEngine returns the engine for the last ContentEngine whose glob matches the relative template path, or the TemplateEngine
+func (h RootHandler) contentEngine(relPath string) Engine {
+	engine := h.TemplateEngine
+	for _, content := range h.ContentEngines {
+		if manifest.Match(content.Glob, relPath) {
+			engine = content.Engine
+		}
+	}
+	return engine
+}
+
+// isBinaryFile sniffs the start of the file, treating it as binary if it contains a NUL byte or isn't detected as text
+func isBinaryFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	sample := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	sample = sample[:n]
+
+	if n == 0 {
+		return false, nil
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true, nil
+	}
+	return !strings.HasPrefix(http.DetectContentType(sample), "text/"), nil
+}
+
+func copyFile(path string, wr io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(wr, f)
+	return err
+}
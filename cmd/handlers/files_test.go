@@ -0,0 +1,109 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers/mocks"
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var pngHeader = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0x0d, 'I', 'H', 'D', 'R', '{', '{'}
+
+func TestIsBinaryFile(t *testing.T) {
+	dir := createTempPath(t, "test-files-")
+	defer os.RemoveAll(dir)
+
+	cases := map[string][]byte{
+		"text.txt":  []byte("Hello {{ .project.name }}\n"),
+		"empty.txt": {},
+		"image.png": pngHeader,
+		"nul.bin":   []byte("abc\x00def"),
+		"latin.txt": []byte("caf\xe9"),
+	}
+	expected := map[string]bool{"text.txt": false, "empty.txt": false, "image.png": true, "nul.bin": true, "latin.txt": false}
+
+	for name, contents := range cases {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), contents, 0644))
+
+		binary, err := isBinaryFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, expected[name], binary, name)
+	}
+}
+
+func TestProcessTemplateCopiesBinaryAndCopyOnlyFilesVerbatim(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	outputPath := createTempPath(t, "test-output-folder-")
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+	defer os.RemoveAll(outputPath)
+
+	require.NoError(t, os.Mkdir(filepath.Join(templatePath, "charts"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "logo.png"), pngHeader, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "charts", "deployment.yaml"), []byte("name: {{ .Release.Name }}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "readme.md"), []byte("{{ .name }}"), 0644))
+
+	mockConfig.On("Object").Return("")
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(func(path string, settings interface{}) string { return path }, nil)
+	mockEngine.On("ParseAndExecuteFile", filepath.Join(templatePath, "readme.md"), mock.Anything, mock.Anything).Return(nil)
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+	handler.Manifest = manifest.Manifest{CopyOnly: []string{"charts/**"}}
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+	mockEngine.AssertExpectations(t)
+
+	logo, err := ioutil.ReadFile(filepath.Join(outputPath, "logo.png"))
+	require.NoError(t, err)
+	assert.Equal(t, pngHeader, logo)
+
+	chart, err := ioutil.ReadFile(filepath.Join(outputPath, "charts", "deployment.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "name: {{ .Release.Name }}", string(chart))
+}
+
+func TestProcessTemplateUsesContentEngineForMatchingFiles(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	workflowEngine := new(mocks.Engine)
+	outputPath := createTempPath(t, "test-output-folder-")
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+	defer os.RemoveAll(outputPath)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "build.yml"), []byte("${{ github.sha }}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "readme.md"), []byte("{{ .name }}"), 0644))
+
+	mockConfig.On("Object").Return("")
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(func(path string, settings interface{}) string { return path }, nil)
+	mockEngine.On("ParseAndExecuteFile", filepath.Join(templatePath, "readme.md"), mock.Anything, mock.Anything).Return(nil)
+	workflowEngine.On("ParseAndExecuteFile", filepath.Join(templatePath, "build.yml"), mock.Anything, mock.Anything).Return(nil)
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+	handler.ContentEngines = []ContentEngine{{Glob: "*.yml", Engine: workflowEngine}}
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+	mockEngine.AssertExpectations(t)
+	workflowEngine.AssertExpectations(t)
+}
@@ -0,0 +1,131 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/pkg/errors"
+)
+
+// HookStage identifies when a hook is run
+type HookStage string
+
+const (
+	// PreHooks are run before the template is processed
+	PreHooks HookStage = "pre"
+	// PostHooks are run after the template has been processed
+	PostHooks HookStage = "post"
+)
+
+// ConfirmHooks shows the user every hook the template would run and asks whether they should be allowed
+func (h RootHandler) ConfirmHooks() (bool, error) {
+	if h.Manifest.Hooks.Empty() {
+		return true, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("This template wants to run the following commands:\n")
+	for _, stage := range []HookStage{PreHooks, PostHooks} {
+		commands, err := h.RenderHooks(stage)
+		if err != nil {
+			return false, err
+		}
+		for _, command := range commands {
+			fmt.Fprintf(&sb, "  (%v) %v\n", stage, command)
+		}
+	}
+	sb.WriteString("Allow these commands to run?")
+
+	return h.IO.Confirm(sb.String())
+}
+
+// RenderHooks renders the hook commands for the stage as templates using the current configuration
+func (h RootHandler) RenderHooks(stage HookStage) ([]string, error) {
+	var rendered []string
+	for _, command := range h.hooksFor(stage) {
+		result, err := h.TemplateEngine.ParseAndExecutePath(command, h.Config.Object())
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error rendering %v hook '%v'", stage, command)
+		}
+		if strings.TrimSpace(result) != "" {
+			rendered = append(rendered, result)
+		}
+	}
+	return rendered, nil
+}
+
+// RunHooks renders and runs the hook commands for the stage in a shell, using outputPath as the working directory.
+// Every template value is exposed to the commands as a STENCIL_VAR_ environment variable.
+func (h RootHandler) RunHooks(stage HookStage, outputPath string) error {
+	commands, err := h.RenderHooks(stage)
+	if err != nil || len(commands) == 0 {
+		return err
+	}
+
+	env, err := h.hookEnvironment()
+	if err != nil {
+		return err
+	}
+
+	for _, command := range commands {
+		fmt.Printf("Running %v hook: %v\n", stage, command)
+
+		cmd := shellCommand(command)
+		cmd.Dir = outputPath
+		cmd.Env = env
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return errors.Wrapf(err, "Error running %v hook '%v'", stage, command)
+		}
+	}
+
+	return nil
+}
+
+func (h RootHandler) hooksFor(stage HookStage) []string {
+	if stage == PreHooks {
+		return h.Manifest.Hooks.Pre
+	}
+	return h.Manifest.Hooks.Post
+}
+
+func (h RootHandler) hookEnvironment() ([]string, error) {
+	settings, err := h.Config.GetAllValues()
+	if err != nil {
+		return nil, err
+	}
+
+	env := os.Environ()
+	for _, setting := range settings {
+		name := confighelper.EnvPrefix + strings.ReplaceAll(setting.Name, ".", "__")
+		env = append(env, name+"="+confighelper.FormatValue(setting.Value))
+	}
+	return env, nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
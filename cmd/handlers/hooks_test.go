@@ -0,0 +1,106 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHooksRunsRenderedCommandsInOutputPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return // Commands below rely on a POSIX shell
+	}
+
+	mockEngine, mockConfig, mockIO := createMocks()
+	outputPath := createTempPath(t, "test-output-folder-")
+	defer os.RemoveAll(outputPath)
+
+	mockConfig.On("Object").Return("")
+	mockConfig.On("GetAllValues").Return([]confighelper.Setting{{Name: "project.name", Value: "foo"}}, nil)
+	mockEngine.On("ParseAndExecutePath", "echo {{ .project.name }} > rendered.txt", mock.Anything).Return("echo foo > rendered.txt", nil)
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(func(path string, settings interface{}) string { return path }, nil)
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+	handler.Manifest = manifest.Manifest{Hooks: manifest.Hooks{Post: []string{
+		"echo {{ .project.name }} > rendered.txt",
+		"echo $STENCIL_VAR_project__name > env.txt",
+	}}}
+
+	err := handler.RunHooks(PostHooks, outputPath)
+	require.NoError(t, err)
+
+	rendered, err := ioutil.ReadFile(filepath.Join(outputPath, "rendered.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo\n", string(rendered))
+
+	env, err := ioutil.ReadFile(filepath.Join(outputPath, "env.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo\n", string(env))
+}
+
+func TestRunHooksReturnsErrorWhenCommandFails(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	outputPath := createTempPath(t, "test-output-folder-")
+	defer os.RemoveAll(outputPath)
+
+	mockConfig.On("Object").Return("")
+	mockConfig.On("GetAllValues").Return([]confighelper.Setting{}, nil)
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return("exit 3", nil)
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+	handler.Manifest = manifest.Manifest{Hooks: manifest.Hooks{Pre: []string{"exit 3"}}}
+
+	err := handler.RunHooks(PreHooks, outputPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Error running pre hook 'exit 3'")
+}
+
+func TestConfirmHooksDoesntAskWhenThereAreNoHooks(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+
+	allowed, err := handler.ConfirmHooks()
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	mockIO.AssertNotCalled(t, "Confirm", mock.Anything)
+}
+
+func TestConfirmHooksShowsRenderedCommands(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+
+	mockConfig.On("Object").Return("")
+	mockEngine.On("ParseAndExecutePath", "git init {{ .name }}", mock.Anything).Return("git init foo", nil)
+	mockIO.On("Confirm", mock.MatchedBy(func(message string) bool {
+		return assert.Contains(t, message, "(post) git init foo")
+	})).Return(false, nil)
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+	handler.Manifest = manifest.Manifest{Hooks: manifest.Hooks{Post: []string{"git init {{ .name }}"}}}
+
+	allowed, err := handler.ConfirmHooks()
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	mockIO.AssertExpectations(t)
+}
@@ -0,0 +1,150 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/pkg/errors"
+)
+
+const (
+	// IgnoreFile lists template paths, using gitignore patterns, that aren't part of the generated project.
+	// Like a .gitignore it can be put in any directory of the template, and its patterns are relative to that directory.
+	IgnoreFile = ".stencilignore"
+	// GitignoreFile is the template's own .gitignore, whose patterns are also used when the manifest sets useGitignore
+	GitignoreFile = ".gitignore"
+)
+
+// ignoreSource is the patterns read from a single ignore file
+type ignoreSource struct {
+	domain   []string
+	priority int
+	patterns []gitignore.Pattern
+}
+
+// templateIgnore decides which paths of the template are left out of the generated project
+type templateIgnore struct {
+	matcher gitignore.Matcher
+}
+
+// loadIgnore reads every .stencilignore in the template, and every .gitignore if the manifest asks for them. Patterns in deeper directories
+// take priority over those above them, and a .stencilignore takes priority over the .gitignore next to it.
+func (h RootHandler) loadIgnore(templatePath string) (templateIgnore, error) {
+	var sources []ignoreSource
+
+	err := filepath.Walk(templatePath,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return errors.Wrapf(err, "Error while walking into directory %v", path)
+			}
+			relPath, err := filepath.Rel(templatePath, path)
+			if err != nil {
+				return errors.Wrap(err, "Error getting relative path")
+			}
+			if info.IsDir() {
+				if relPath != "." && isAlwaysIgnored(splitPath(relPath)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			priority := 1
+			switch {
+			case info.Name() == IgnoreFile:
+			case info.Name() == GitignoreFile && h.Manifest.UseGitignore:
+				priority = 0
+			default:
+				return nil
+			}
+
+			var domain []string
+			if dir := filepath.Dir(relPath); dir != "." {
+				domain = splitPath(dir)
+			}
+			patterns, err := readIgnorePatterns(path, domain)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, ignoreSource{domain: domain, priority: priority, patterns: patterns})
+			return nil
+		})
+	if err != nil {
+		return templateIgnore{}, err
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		if len(sources[i].domain) != len(sources[j].domain) {
+			return len(sources[i].domain) < len(sources[j].domain)
+		}
+		return sources[i].priority < sources[j].priority
+	})
+
+	var patterns []gitignore.Pattern
+	for _, source := range sources {
+		patterns = append(patterns, source.patterns...)
+	}
+	return templateIgnore{matcher: gitignore.NewMatcher(patterns)}, nil
+}
+
+// ignored reports whether the path, relative to the template, is left out of the generated project
+func (i templateIgnore) ignored(relPath string, isDir bool) bool {
+	segments := splitPath(relPath)
+	if isAlwaysIgnored(segments) || segments[len(segments)-1] == IgnoreFile {
+		return true
+	}
+	return i.matcher != nil && i.matcher.Match(segments, isDir)
+}
+
+// isAlwaysIgnored reports whether the path is part of git, or is the template's .stencil directory
+func isAlwaysIgnored(segments []string) bool {
+	if segments[0] == ".stencil" {
+		return true
+	}
+	for _, segment := range segments {
+		if segment == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+func readIgnorePatterns(path string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error reading ignore file %v", path)
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+	return patterns, errors.Wrapf(scanner.Err(), "Error reading ignore file %v", path)
+}
+
+func splitPath(relPath string) []string {
+	return strings.Split(filepath.ToSlash(relPath), "/")
+}
@@ -0,0 +1,98 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTemplateOnlyIgnoresGitAndStencilDirectories(t *testing.T) {
+	files := generateIgnoreTest(t, manifest.Manifest{}, map[string]string{
+		".stencil/.stencil.json":       "{}",
+		".git/config":                  "",
+		"sub/.git":                     "gitdir: ../.git/modules/sub",
+		".github/workflows/ci.yml":     "on: push",
+		".gitignore":                   "bin/",
+		".gitattributes":               "* text=auto",
+		"docs/.stencil/notes.md":       "notes",
+		".stencil-templates/readme.md": "readme",
+	})
+
+	assert.Equal(t, []string{".gitattributes", ".github/workflows/ci.yml", ".gitignore", ".stencil-templates/readme.md", "docs/.stencil/notes.md"}, files)
+}
+
+func TestProcessTemplateSkipsPathsInStencilIgnore(t *testing.T) {
+	files := generateIgnoreTest(t, manifest.Manifest{}, map[string]string{
+		IgnoreFile:                 "# Build output\n*.log\n!keep.log\n/build/\n**/cache/**\n",
+		"debug.log":                "",
+		"keep.log":                 "",
+		"build/out.txt":            "",
+		"src/build/main.go":        "",
+		"src/cache/a/b.txt":        "",
+		"src/" + IgnoreFile:        "local.txt\n",
+		"src/local.txt":            "",
+		"local.txt":                "",
+		"src/nested/local.txt":     "",
+		"src/nested/kept.txt":      "",
+		"src/nested/" + IgnoreFile: "!local.txt\n",
+	})
+
+	assert.Equal(t, []string{"keep.log", "local.txt", "src/build/main.go", "src/nested/kept.txt", "src/nested/local.txt"}, files)
+}
+
+func TestProcessTemplateUsesGitignoreWhenAsked(t *testing.T) {
+	template := map[string]string{
+		GitignoreFile:    "secret.txt\nnode_modules/\n*.tmp\n",
+		IgnoreFile:       "!*.tmp\n",
+		"secret.txt":     "",
+		"node_modules/x": "",
+		"scratch.tmp":    "",
+	}
+
+	files := generateIgnoreTest(t, manifest.Manifest{}, template)
+	assert.Equal(t, []string{".gitignore", "node_modules/x", "scratch.tmp", "secret.txt"}, files)
+
+	files = generateIgnoreTest(t, manifest.Manifest{UseGitignore: true}, template)
+	assert.Equal(t, []string{".gitignore", "scratch.tmp"}, files)
+}
+
+// generateIgnoreTest creates a template with the files, processes it and returns the generated files as sorted slash separated paths
+func generateIgnoreTest(t *testing.T, m manifest.Manifest, files map[string]string) []string {
+	handler, templatePath, outputPath := createHandlerTest(t, `{}`)
+
+	writeFiles(t, templatePath, files)
+	handler.Manifest = m
+
+	require.NoError(t, handler.ProcessTemplate(templatePath, outputPath))
+
+	var generated []string
+	err := filepath.Walk(outputPath, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(outputPath, path)
+			generated = append(generated, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	require.NoError(t, err)
+	sort.Strings(generated)
+	return generated
+}
@@ -0,0 +1,105 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// mergeThreeWay merges the changes made from base to ours and from base to theirs, line by line, in the style of diff3.
+// Where both sides changed the same lines differently, the change is wrapped in conflict markers showing the existing lines,
+// the lines from base and the lines from the template, and conflicted is true.
+func mergeThreeWay(base, ours, theirs string) (merged []byte, conflicted bool) {
+	baseLines := splitLines(base)
+	ourLines, ourMatches := matchLines(base, ours)
+	theirLines, theirMatches := matchLines(base, theirs)
+
+	var buf bytes.Buffer
+	b, o, t := 0, 0, 0
+	emit := func(baseEnd, ourEnd, theirEnd int) {
+		baseChunk, ourChunk, theirChunk := baseLines[b:baseEnd], ourLines[o:ourEnd], theirLines[t:theirEnd]
+		switch {
+		case equalLines(ourChunk, baseChunk):
+			writeChunk(&buf, theirChunk)
+		case equalLines(theirChunk, baseChunk), equalLines(ourChunk, theirChunk):
+			writeChunk(&buf, ourChunk)
+		default:
+			conflicted = true
+			buf.WriteString("<<<<<<< existing\n")
+			writeLines(&buf, strings.Join(ourChunk, ""))
+			buf.WriteString("||||||| base\n")
+			writeLines(&buf, strings.Join(baseChunk, ""))
+			buf.WriteString("=======\n")
+			writeLines(&buf, strings.Join(theirChunk, ""))
+			buf.WriteString(">>>>>>> template\n")
+		}
+		b, o, t = baseEnd, ourEnd, theirEnd
+	}
+
+	for i := range baseLines {
+		// A base line kept by both sides is stable, everything before it since the last stable line is a chunk to merge
+		if ourMatches[i] < 0 || theirMatches[i] < 0 {
+			continue
+		}
+		emit(i, ourMatches[i], theirMatches[i])
+		buf.WriteString(baseLines[i])
+		b, o, t = b+1, o+1, t+1
+	}
+	emit(len(baseLines), len(ourLines), len(theirLines))
+
+	return buf.Bytes(), conflicted
+}
+
+// matchLines splits changed into lines, and returns for each line of base the index of the line it was kept as, or -1 if it was removed
+func matchLines(base, changed string) ([]string, []int) {
+	var lines []string
+	var matches []int
+	for _, d := range diff.Do(base, changed) {
+		for _, line := range splitLines(d.Text) {
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				matches = append(matches, len(lines))
+				lines = append(lines, line)
+			case diffmatchpatch.DiffDelete:
+				matches = append(matches, -1)
+			case diffmatchpatch.DiffInsert:
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines, matches
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeChunk(buf *bytes.Buffer, lines []string) {
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeThreeWayCombinesChangesToDifferentLines(t *testing.T) {
+	base := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	ours := "// Local comment\npackage main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	theirs := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+
+	merged, conflicted := mergeThreeWay(base, ours, theirs)
+	assert.False(t, conflicted)
+	assert.Equal(t, "// Local comment\npackage main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n", string(merged))
+}
+
+func TestMergeThreeWayKeepsIdenticalChanges(t *testing.T) {
+	merged, conflicted := mergeThreeWay("a\nb\nc\n", "a\nB\nc\n", "a\nB\nc\n")
+	assert.False(t, conflicted)
+	assert.Equal(t, "a\nB\nc\n", string(merged))
+}
+
+func TestMergeThreeWayMarksConflictingChanges(t *testing.T) {
+	merged, conflicted := mergeThreeWay("a\nb\nc\n", "a\nours\nc\n", "a\ntheirs\nc\nd\n")
+	assert.True(t, conflicted)
+	assert.Equal(t, "a\n<<<<<<< existing\nours\n||||||| base\nb\n=======\ntheirs\n>>>>>>> template\nc\nd\n", string(merged))
+}
+
+func TestMergeThreeWayAddsToEmptyBase(t *testing.T) {
+	merged, conflicted := mergeThreeWay("", "mine\n", "template\n")
+	assert.True(t, conflicted)
+	assert.Equal(t, "<<<<<<< existing\nmine\n||||||| base\n=======\ntemplate\n>>>>>>> template\n", string(merged))
+}
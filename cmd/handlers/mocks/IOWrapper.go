@@ -3,6 +3,7 @@
 package mocks
 
 import confighelper "github.com/Chris-Greaves/stencil/confighelper"
+import manifest "github.com/Chris-Greaves/stencil/manifest"
 import mock "github.com/stretchr/testify/mock"
 
 // IOWrapper is an autogenerated mock type for the IOWrapper type
@@ -10,6 +11,48 @@ type IOWrapper struct {
 	mock.Mock
 }
 
+// Choose provides a mock function with given fields: message, choices
+func (_m *IOWrapper) Choose(message string, choices []string) (string, error) {
+	ret := _m.Called(message, choices)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, []string) string); ok {
+		r0 = rf(message, choices)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []string) error); ok {
+		r1 = rf(message, choices)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Confirm provides a mock function with given fields: message
+func (_m *IOWrapper) Confirm(message string) (bool, error) {
+	ret := _m.Called(message)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(message)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOverrides provides a mock function with given fields: allSettings
 func (_m *IOWrapper) GetOverrides(allSettings []confighelper.Setting) ([]confighelper.Setting, error) {
 	ret := _m.Called(allSettings)
@@ -32,3 +75,26 @@ func (_m *IOWrapper) GetOverrides(allSettings []confighelper.Setting) ([]configh
 
 	return r0, r1
 }
+
+// Prompt provides a mock function with given fields: variable, current
+func (_m *IOWrapper) Prompt(variable manifest.Variable, current interface{}) (interface{}, error) {
+	ret := _m.Called(variable, current)
+
+	var r0 interface{}
+	if rf, ok := ret.Get(0).(func(manifest.Variable, interface{}) interface{}); ok {
+		r0 = rf(variable, current)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(interface{})
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(manifest.Variable, interface{}) error); ok {
+		r1 = rf(variable, current)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,77 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// checkTargetPath makes sure the rendered relative path stays inside the output directory, naming the values used by the path when it doesn't
+func (h RootHandler) checkTargetPath(relPath, rendered string) error {
+	slashed := filepath.ToSlash(rendered)
+	cleaned := filepath.ToSlash(filepath.Clean(rendered))
+
+	var problem string
+	switch {
+	case filepath.IsAbs(rendered) || filepath.VolumeName(rendered) != "" || strings.HasPrefix(slashed, "/"):
+		problem = "is an absolute path"
+	case cleaned == ".." || strings.HasPrefix(cleaned, "../"):
+		problem = "is outside the output directory"
+	default:
+		return nil
+	}
+
+	message := fmt.Sprintf("The template path '%v' renders to '%v', which %v", relPath, rendered, problem)
+
+	left, right := h.delimiters()
+	if refs, err := templateReferences(filepath.ToSlash(relPath), left, right); err == nil && len(refs) > 0 {
+		message += fmt.Sprintf(". Check the value of '%v'", strings.Join(refs, "', '"))
+	}
+	return errors.New(message)
+}
+
+// IsWithin reports whether path is dir or somewhere inside it, following symlinks for the parts that exist
+func IsWithin(path, dir string) bool {
+	path, dir = resolvePath(path), resolvePath(dir)
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// resolvePath makes the path absolute and resolves symlinks in its longest existing prefix
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	var missing []string
+	for current := abs; ; current = filepath.Dir(current) {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...)
+		}
+		if filepath.Dir(current) == current {
+			return abs
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWithin(t *testing.T) {
+	dir := createTempPath(t, "test-paths-")
+	defer os.RemoveAll(dir)
+
+	template := filepath.Join(dir, "template")
+	require.NoError(t, os.Mkdir(template, 0755))
+
+	assert.True(t, IsWithin(template, template))
+	assert.True(t, IsWithin(filepath.Join(template, "out"), template))
+	assert.True(t, IsWithin(filepath.Join(template, "missing", "..", "out"), template))
+	assert.False(t, IsWithin(filepath.Join(dir, "template-out"), template))
+	assert.False(t, IsWithin(dir, template))
+	assert.False(t, IsWithin(filepath.Join(template, "..", "out"), template))
+}
+
+func TestIsWithinFollowsSymlinks(t *testing.T) {
+	dir := createTempPath(t, "test-paths-")
+	defer os.RemoveAll(dir)
+
+	template := filepath.Join(dir, "template")
+	require.NoError(t, os.Mkdir(template, 0755))
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(template, link); err != nil {
+		t.Skip("symlinks aren't supported")
+	}
+
+	assert.True(t, IsWithin(filepath.Join(link, "out"), template))
+}
+
+func TestGetTargetPathRejectsPathsOutsideOutput(t *testing.T) {
+	cases := map[string]string{
+		"../../etc":     "is outside the output directory",
+		"app/../../etc": "is outside the output directory",
+		"..":            "is outside the output directory",
+		"/etc":          "is an absolute path",
+	}
+
+	for rendered, expected := range cases {
+		mockEngine, mockConfig, mockIO := createMocks()
+		mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(rendered+"/passwd", nil)
+		handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+
+		_, err := handler.GetTargetPath("template", "output", filepath.Join("template", "{{ .dir }}", "passwd"), "")
+		if assert.Error(t, err, rendered) {
+			assert.Contains(t, err.Error(), expected)
+			assert.Contains(t, err.Error(), "Check the value of 'dir'")
+		}
+	}
+}
+
+func TestGetTargetPathAllowsParentSegmentsInsideOutput(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return("app/../docs/index.md", nil)
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+
+	target, err := handler.GetTargetPath("template", "output", filepath.Join("template", "{{ .dir }}", "index.md"), "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("output", "docs", "index.md"), target)
+}
+
+func TestProcessTemplateRefusesToFollowSymlinksOutOfOutput(t *testing.T) {
+	handler, templatePath, outputPath := createHandlerTest(t, `{}`)
+	outsidePath := createTempPath(t, "test-outside-")
+	defer os.RemoveAll(outsidePath)
+
+	require.NoError(t, os.Mkdir(filepath.Join(templatePath, "link"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "link", "file.txt"), []byte("contents"), 0644))
+	if err := os.Symlink(outsidePath, filepath.Join(outputPath, "link")); err != nil {
+		t.Skip("symlinks aren't supported")
+	}
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a symlink leads outside the output directory")
+	assert.NoFileExists(t, filepath.Join(outsidePath, "file.txt"))
+}
+
+func TestProcessTemplateRefusesToFollowSymlinksInTheTemplate(t *testing.T) {
+	handler, templatePath, outputPath := createHandlerTest(t, `{}`)
+	outsidePath := createTempPath(t, "test-outside-")
+	defer os.RemoveAll(outsidePath)
+
+	secret := filepath.Join(outsidePath, "id_rsa")
+	require.NoError(t, ioutil.WriteFile(secret, []byte("secret"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "readme.md"), []byte("readme"), 0644))
+	if err := os.Symlink(secret, filepath.Join(templatePath, "leak.txt")); err != nil {
+		t.Skip("symlinks aren't supported")
+	}
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'leak.txt' is a symlink")
+	assert.NoFileExists(t, filepath.Join(outputPath, "leak.txt"))
+	assert.NoFileExists(t, filepath.Join(outputPath, "readme.md"))
+
+	err = handler.ValidateTemplate(templatePath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "leak.txt: 'leak.txt' is a symlink")
+}
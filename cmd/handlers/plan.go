@@ -0,0 +1,124 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStatus describes what processing the template would do to a target path
+type FileStatus int
+
+const (
+	// StatusNew means the target does not exist yet and would be created
+	StatusNew FileStatus = iota
+	// StatusOverwritten means the target exists and its contents would be replaced
+	StatusOverwritten
+	// StatusUnchanged means the target exists and already matches the rendered template
+	StatusUnchanged
+	// StatusSkipped means the target exists and differs from the template, but is kept because of the conflict policy
+	StatusSkipped
+	// StatusConflict means the target exists and differs from the template, which stops the run because of the conflict policy
+	StatusConflict
+)
+
+func (s FileStatus) String() string {
+	switch s {
+	case StatusNew:
+		return "new"
+	case StatusOverwritten:
+		return "overwritten"
+	case StatusUnchanged:
+		return "unchanged"
+	case StatusSkipped:
+		return "skipped"
+	case StatusConflict:
+		return "conflict"
+	default:
+		return "unknown"
+	}
+}
+
+// PlannedFile is a single entry in the plan produced by PlanTemplate
+type PlannedFile struct {
+	SourcePath string
+	TargetPath string
+	RelPath    string
+	IsDir      bool
+	Status     FileStatus
+}
+
+// PrintPlan writes the plan to w as an indented tree rooted at outputPath, annotating each entry with its status
+func PrintPlan(w io.Writer, outputPath string, plan []PlannedFile) {
+	sorted := make([]PlannedFile, len(plan))
+	copy(sorted, plan)
+	sort.Slice(sorted, func(i, j int) bool {
+		return lessBySegment(sorted[i].RelPath, sorted[j].RelPath)
+	})
+
+	counts := map[FileStatus]int{}
+
+	fmt.Fprintf(w, "%v\n", outputPath)
+	for _, entry := range sorted {
+		rel := filepath.ToSlash(entry.RelPath)
+		depth := strings.Count(rel, "/") + 1
+		name := filepath.Base(entry.RelPath)
+		if entry.IsDir {
+			name += "/"
+		}
+		fmt.Fprintf(w, "%v%v (%v)\n", strings.Repeat("  ", depth), name, entry.Status)
+
+		if !entry.IsDir {
+			counts[entry.Status]++
+		}
+	}
+
+	fmt.Fprintf(w, "\n%v new, %v overwritten, %v unchanged", counts[StatusNew], counts[StatusOverwritten], counts[StatusUnchanged])
+	if counts[StatusSkipped] > 0 {
+		fmt.Fprintf(w, ", %v skipped", counts[StatusSkipped])
+	}
+	if counts[StatusConflict] > 0 {
+		fmt.Fprintf(w, ", %v conflicts", counts[StatusConflict])
+	}
+	fmt.Fprintln(w)
+}
+
+// changedStatus is the status of an existing file that differs from the template, which depends on the conflict policy
+func (h RootHandler) changedStatus() FileStatus {
+	switch h.Conflicts {
+	case ConflictSkip:
+		return StatusSkipped
+	case ConflictFail:
+		return StatusConflict
+	default:
+		return StatusOverwritten
+	}
+}
+
+// lessBySegment orders paths one segment at a time so that a directory's children always follow it directly
+func lessBySegment(a, b string) bool {
+	aParts := strings.Split(filepath.ToSlash(a), "/")
+	bParts := strings.Split(filepath.ToSlash(b), "/")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] != bParts[i] {
+			return aParts[i] < bParts[i]
+		}
+	}
+	return len(aParts) < len(bParts)
+}
@@ -0,0 +1,144 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/pkg/errors"
+)
+
+// scopedConfig adds the current item of a repeat to the configuration, for rendering the paths being repeated
+type scopedConfig struct {
+	Config
+	name  string
+	value interface{}
+	// scope describes the item, e.g. "entities[2]", including the items of any repeats around it
+	scope string
+}
+
+// Object returns the configuration with the item added, hiding any value of the same name
+func (c scopedConfig) Object() interface{} {
+	values := map[string]interface{}{}
+	if parent, ok := c.Config.Object().(map[string]interface{}); ok {
+		for name, value := range parent {
+			values[name] = value
+		}
+	}
+	values[c.name] = c.value
+	return values
+}
+
+// walkPaths walks root, a path inside the template, skipping ignored paths and calling visit with each path and the handler to process
+// it with. Paths matching a repeat in the manifest are walked once for every item, with a handler whose configuration includes the item.
+// Errors finding a repeat's items, and symlinks, are passed to report when it is set, skipping the path, otherwise they stop the walk.
+func (h RootHandler) walkPaths(root, templatePath string, ignore templateIgnore, report func(relPath, part string, err error),
+	visit func(h RootHandler, path, relPath string, info os.FileInfo) error) error {
+	return filepath.Walk(root,
+		func(path string, info os.FileInfo, err error) error {
+			if path == templatePath {
+				return nil
+			}
+
+			if err != nil {
+				return errors.Wrapf(err, "Error while walking into directory %v", path)
+			}
+
+			relPath, err := filepath.Rel(templatePath, path)
+			if err != nil {
+				return errors.Wrap(err, "Error getting relative path")
+			}
+			if ignore.ignored(relPath, info.IsDir()) {
+				return skip(info)
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				// Following it would copy whatever it points at, e.g. a file from the user's home directory, into the project
+				err = fmt.Errorf("'%v' is a symlink, templates can't contain symlinks as they could read files from outside the template", filepath.ToSlash(relPath))
+				if report == nil {
+					return err
+				}
+				report(relPath, "", err)
+				return nil
+			}
+
+			// The root of a walk for a single item has already been matched
+			repeat, repeated := h.Manifest.RepeatFor(relPath)
+			if !repeated || (path == root && h.inRepeat()) {
+				return visit(h, path, relPath, info)
+			}
+
+			items, err := h.repeatItems(repeat)
+			if err != nil {
+				if report == nil {
+					return err
+				}
+				report(relPath, "repeat", err)
+				return skip(info)
+			}
+			for i, item := range items {
+				scoped := h
+				scoped.Config = scopedConfig{Config: h.Config, name: repeat.ItemName(), value: item, scope: h.itemScope(repeat, i)}
+				if err = scoped.walkPaths(path, templatePath, ignore, report, visit); err != nil {
+					return err
+				}
+			}
+			return skip(info)
+		})
+}
+
+// repeatItems finds the list of items the repeat generates its paths for
+func (h RootHandler) repeatItems(repeat manifest.Repeat) ([]interface{}, error) {
+	value, found := lookupValue(h.Config.Object(), repeat.Foreach)
+	if !found {
+		return nil, fmt.Errorf("Error repeating '%v', there is no value named '%v'", repeat.Glob, repeat.Foreach)
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Error repeating '%v', the value '%v' must be a list", repeat.Glob, repeat.Foreach)
+	}
+	return items, nil
+}
+
+func (h RootHandler) inRepeat() bool {
+	_, ok := h.Config.(scopedConfig)
+	return ok
+}
+
+// itemScope describes the item at index of the repeat, following the items of the repeats it is inside
+func (h RootHandler) itemScope(repeat manifest.Repeat, index int) string {
+	scope := fmt.Sprintf("%v[%v]", repeat.Foreach, index)
+	if parent, ok := h.Config.(scopedConfig); ok {
+		scope = parent.scope + " " + scope
+	}
+	return scope
+}
+
+// lookupValue finds the value with the dotted name, e.g. "db.tables", in the configuration
+func lookupValue(object interface{}, name string) (interface{}, bool) {
+	for _, key := range strings.Split(name, ".") {
+		values, ok := object.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if object, ok = values[key]; !ok {
+			return nil, false
+		}
+	}
+	return object, true
+}
@@ -0,0 +1,137 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const repeatConf = `{
+	"module": "acme",
+	"entities": [
+		{ "name": "user", "api": true, "fields": ["id", "email"] },
+		{ "name": "order", "api": false, "fields": ["id"] }
+	]
+}`
+
+func TestProcessTemplateRepeatsPathsForEachItem(t *testing.T) {
+	handler, templatePath, outputPath := createRepeatTest(t, repeatConf, map[string]string{
+		"main.go":                              "package {{ .module }}\n",
+		"handlers/{{ .entity.name }}.go":       "// {{ .entity.name }} in {{ .module }}\n",
+		"migrations/{{ .entity.name }}/up.sql": "create table {{ .entity.name }}s;\n",
+	})
+	handler.Manifest.Repeat = []manifest.Repeat{
+		{Glob: "handlers/{{ .entity.name }}.go", Foreach: "entities", As: "entity"},
+		{Glob: "migrations/*", Foreach: "entities", As: "entity"},
+	}
+
+	require.NoError(t, handler.ProcessTemplate(templatePath, outputPath))
+
+	assertContents(t, filepath.Join(outputPath, "main.go"), "package acme\n")
+	assertContents(t, filepath.Join(outputPath, "handlers", "user.go"), "// user in acme\n")
+	assertContents(t, filepath.Join(outputPath, "handlers", "order.go"), "// order in acme\n")
+	assertContents(t, filepath.Join(outputPath, "migrations", "user", "up.sql"), "create table users;\n")
+	assertContents(t, filepath.Join(outputPath, "migrations", "order", "up.sql"), "create table orders;\n")
+}
+
+func TestProcessTemplateRepeatsInsideRepeats(t *testing.T) {
+	handler, templatePath, outputPath := createRepeatTest(t, repeatConf, map[string]string{
+		"{{ .entity.name }}/{{ .item }}.txt": "{{ .entity.name }}.{{ .item }}",
+	})
+	handler.Manifest.Repeat = []manifest.Repeat{
+		{Glob: "{{ .entity.name }}", Foreach: "entities", As: "entity"},
+		{Glob: "{{ .entity.name }}/*", Foreach: "entity.fields"},
+	}
+
+	require.NoError(t, handler.ProcessTemplate(templatePath, outputPath))
+
+	assertContents(t, filepath.Join(outputPath, "user", "id.txt"), "user.id")
+	assertContents(t, filepath.Join(outputPath, "user", "email.txt"), "user.email")
+	assertContents(t, filepath.Join(outputPath, "order", "id.txt"), "order.id")
+	assert.False(t, fileExists(filepath.Join(outputPath, "order", "email.txt")))
+}
+
+func TestProcessTemplateAppliesRulesToEachItem(t *testing.T) {
+	handler, templatePath, outputPath := createRepeatTest(t, repeatConf, map[string]string{
+		"api/{{ .entity.name }}.go": "",
+	})
+	handler.Manifest.Repeat = []manifest.Repeat{{Glob: "api/*", Foreach: "entities", As: "entity"}}
+	handler.Manifest.Rules = []manifest.Rule{{Include: "api/*", When: "{{ .entity.api }}"}}
+
+	require.NoError(t, handler.ProcessTemplate(templatePath, outputPath))
+
+	assert.True(t, fileExists(filepath.Join(outputPath, "api", "user.go")))
+	assert.False(t, fileExists(filepath.Join(outputPath, "api", "order.go")))
+}
+
+func TestProcessTemplateErrorsWhenItemsShareAPath(t *testing.T) {
+	handler, templatePath, outputPath := createRepeatTest(t, repeatConf, map[string]string{
+		"handler.go": "{{ .entity.name }}",
+	})
+	handler.Manifest.Repeat = []manifest.Repeat{{Glob: "handler.go", Foreach: "entities", As: "entity"}}
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "for both entities[0] and entities[1]")
+	assert.False(t, fileExists(filepath.Join(outputPath, "handler.go")))
+}
+
+func TestProcessTemplateErrorsWhenRepeatingSomethingOtherThanAList(t *testing.T) {
+	handler, templatePath, outputPath := createRepeatTest(t, repeatConf, map[string]string{
+		"{{ .item }}.txt": "",
+	})
+
+	handler.Manifest.Repeat = []manifest.Repeat{{Glob: "*.txt", Foreach: "module"}}
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the value 'module' must be a list")
+
+	handler.Manifest.Repeat = []manifest.Repeat{{Glob: "*.txt", Foreach: "missing"}}
+	err = handler.ProcessTemplate(templatePath, outputPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "there is no value named 'missing'")
+}
+
+func TestValidateTemplateReportsProblemsInRepeatedPathsOnce(t *testing.T) {
+	handler, templatePath, _ := createRepeatTest(t, repeatConf, map[string]string{
+		"{{ .entity.name }}.go": "{{ .entity.name.first }}",
+		"{{ .item }}.txt":       "",
+	})
+	handler.Manifest.Repeat = []manifest.Repeat{
+		{Glob: "*.go", Foreach: "entities", As: "entity"},
+		{Glob: "*.txt", Foreach: "nothing"},
+	}
+
+	err := handler.ValidateTemplate(templatePath)
+	require.Error(t, err)
+
+	problems := err.(ValidationError).Problems
+	require.Len(t, problems, 2)
+	assert.Equal(t, "{{ .entity.name }}.go", problems[0].Path)
+	assert.Contains(t, problems[0].Message, "can't evaluate field first")
+	assert.Equal(t, TemplateProblem{Path: "{{ .item }}.txt", Part: "repeat", Message: "Error repeating '*.txt', there is no value named 'nothing'"}, problems[1])
+}
+
+// createRepeatTest creates a template with the files, an empty output directory and a handler using the configuration
+func createRepeatTest(t *testing.T, conf string, files map[string]string) (RootHandler, string, string) {
+	handler, templatePath, outputPath := createHandlerTest(t, conf)
+	writeFiles(t, templatePath, files)
+	return handler, templatePath, outputPath
+}
@@ -15,13 +15,16 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/Chris-Greaves/stencil/manifest"
 	"github.com/pkg/errors"
 )
 
@@ -41,6 +44,15 @@ type Engine interface {
 // IOWrapper is a wrapper around the Input / Output for Stencil
 type IOWrapper interface {
 	GetOverrides(allSettings []confighelper.Setting) ([]confighelper.Setting, error)
+	Prompt(variable manifest.Variable, current interface{}) (interface{}, error)
+	Confirm(message string) (bool, error)
+	Choose(message string, choices []string) (string, error)
+}
+
+// ContentEngine is used in place of the TemplateEngine to render the contents of files matching Glob
+type ContentEngine struct {
+	Glob   string
+	Engine Engine
 }
 
 // RootHandler is the Handler object for the Root cm
@@ -48,6 +60,12 @@ type RootHandler struct {
 	Config         Config
 	TemplateEngine Engine
 	IO             IOWrapper
+	Manifest       manifest.Manifest
+	ContentEngines []ContentEngine
+	Conflicts      ConflictPolicy
+	// Provided are the names of the values given up front, with --set, --values, the environment or a provenance record,
+	// which are used as they are rather than rendered as templates
+	Provided map[string]bool
 }
 
 // NewRootHandler creates and returns a new RootHandler instance
@@ -55,67 +73,208 @@ func NewRootHandler(conf Config, templateEngine Engine, io IOWrapper) RootHandle
 	return RootHandler{Config: conf, TemplateEngine: templateEngine, IO: io}
 }
 
-// OfferConfigOverrides will take the current configuration and offer the user the ability to override the default values
+// ProvideValues sets values given up front in the configuration, returning a handler that keeps them as they are
+func (h RootHandler) ProvideValues(settings []confighelper.Setting) (RootHandler, error) {
+	if err := h.Config.SetValues(settings); err != nil {
+		return h, err
+	}
+
+	provided := map[string]bool{}
+	for name := range h.Provided {
+		provided[name] = true
+	}
+	for _, setting := range settings {
+		provided[setting.Name] = true
+	}
+	h.Provided = provided
+	return h, nil
+}
+
+// OfferConfigOverrides will offer the user the ability to override each value in the configuration, asking for the variables in the
+// manifest using their prompts. Values are asked for in dependency order, so computed defaults can use the answers they refer to.
 func (h RootHandler) OfferConfigOverrides() error {
-	editableSettings, err := h.Config.GetAllValues()
+	return h.resolveValues(true)
+}
+
+// ProcessTemplate will walk through the Template and Parse it using the existing configuration.
+// Files that already exist in the output are handled using the Conflicts policy. The project is generated in a staging directory
+// and only moved into the output once the whole template has been processed, so an error leaves the output as it was.
+func (h RootHandler) ProcessTemplate(templatePath, outputPath string) error {
+	stage, err := newStaging(outputPath)
 	if err != nil {
 		return err
 	}
+	defer stage.cleanup()
+
+	resolver := &conflictResolver{}
+
+	err = h.walkTemplate(templatePath, outputPath,
+		func(h RootHandler, path, relPath, targetPath string, info os.FileInfo) error {
+			stagedPath, err := stage.path(outputPath, targetPath)
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				fmt.Printf("Creating %v -> %v\n", path, targetPath)
+
+				// If its a Directory, create the directory in the target
+				if err := os.MkdirAll(stagedPath, info.Mode()); err != nil {
+					return errors.Wrapf(err, "Error making directory %v", path)
+				}
+				return nil
+			}
 
-	updatedSets, err := h.IO.GetOverrides(editableSettings)
+			var contents []byte
+			if h.Conflicts != ConflictOverwrite && fileExists(targetPath) {
+				resolved, err := resolver.resolve(h, path, relPath, targetPath)
+				if err != nil || resolved == nil {
+					return err
+				}
+				contents = resolved
+			}
+
+			fmt.Printf("Creating %v -> %v\n", path, targetPath)
+
+			// Open the file to write the contents into.
+			destinationFile, err := os.OpenFile(stagedPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+			if err != nil {
+				return errors.Wrapf(err, "Error creating file at '%v'", targetPath)
+			}
+			defer destinationFile.Close()
+
+			if contents != nil {
+				if _, err = destinationFile.Write(contents); err != nil {
+					return errors.Wrapf(err, "Error writing file at '%v'", targetPath)
+				}
+				return nil
+			}
+
+			// If its a file, parse and execute the file and copy the result to the target
+			return h.writeFile(path, relPath, destinationFile)
+		})
 	if err != nil {
 		return err
 	}
 
-	h.Config.SetValues(updatedSets)
-
-	return nil
+	return stage.commit(outputPath)
 }
 
-// ProcessTemplate will walk through the Template and Parse it using the existing configuration
-func (h RootHandler) ProcessTemplate(templatePath, outputPath string) error {
-	return filepath.Walk(templatePath,
-		func(path string, info os.FileInfo, err error) error {
-			// Skip if root or part of git
-			if path == templatePath || shouldBeIgnored(path) {
+// PlanTemplate walks through the Template exactly like ProcessTemplate, rendering every file in memory, and reports what would happen
+// to each target path without writing anything to disk.
+func (h RootHandler) PlanTemplate(templatePath, outputPath string) ([]PlannedFile, error) {
+	var plan []PlannedFile
+
+	err := h.walkTemplate(templatePath, outputPath,
+		func(h RootHandler, path, relPath, targetPath string, info os.FileInfo) error {
+			relTarget, err := filepath.Rel(outputPath, targetPath)
+			if err != nil {
+				return errors.Wrap(err, "Error getting relative path")
+			}
+			planned := PlannedFile{SourcePath: path, TargetPath: targetPath, RelPath: relTarget, IsDir: info.IsDir()}
+
+			existing, statErr := os.Stat(targetPath)
+			if info.IsDir() {
+				planned.Status = StatusNew
+				if statErr == nil && existing.IsDir() {
+					planned.Status = StatusUnchanged
+				}
+				plan = append(plan, planned)
 				return nil
 			}
 
+			buf := new(bytes.Buffer)
+			if err := h.writeFile(path, relPath, buf); err != nil {
+				return err
+			}
+
+			planned.Status = StatusNew
+			if statErr == nil {
+				planned.Status = h.changedStatus()
+				if current, err := ioutil.ReadFile(targetPath); err == nil && bytes.Equal(current, buf.Bytes()) {
+					planned.Status = StatusUnchanged
+				}
+			}
+			plan = append(plan, planned)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// walkTemplate walks the template, skipping ignored paths (see loadIgnore), and calls fn with each source path, its resolved target path
+// and the handler to render it with. Paths repeated for the items of a list are passed once per item, each with its own handler.
+func (h RootHandler) walkTemplate(templatePath, outputPath string, fn func(h RootHandler, path, relPath, targetPath string, info os.FileInfo) error) error {
+	ignore, err := h.loadIgnore(templatePath)
+	if err != nil {
+		return err
+	}
+
+	// Files generated for different items of a repeat must not overwrite each other
+	generatedBy := map[string]string{}
+
+	return h.walkPaths(templatePath, templatePath, ignore, nil,
+		func(h RootHandler, path, relPath string, info os.FileInfo) error {
+			included, err := h.isIncluded(relPath)
 			if err != nil {
-				return errors.Wrapf(err, "Error while walking into directory %v", path)
+				return err
+			}
+			if !included {
+				return skip(info)
 			}
 
 			targetPath, err := h.GetTargetPath(templatePath, outputPath, path, h.Config.Object())
 			if err != nil {
 				return err
 			}
+			if targetPath == "" {
+				// Part of the path rendered to nothing, so the template has chosen not to create it
+				return skip(info)
+			}
+			if !IsWithin(targetPath, outputPath) {
+				return fmt.Errorf("Refusing to write '%v', a symlink leads outside the output directory", targetPath)
+			}
 
-			fmt.Printf("Creating %v -> %v\n", path, targetPath)
-
-			if info.IsDir() {
-				// If its a Directory, create the directory in the target
-				if err = os.MkdirAll(targetPath, info.Mode()); err != nil {
-					return errors.Wrapf(err, "Error making directory %v", path)
-				}
-			} else {
-				// Open the file to write the contents into.
-				destinationFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
-				if err != nil {
-					return errors.Wrapf(err, "Error creating file at '%v'", targetPath)
-				}
-				defer destinationFile.Close()
-
-				// If its a file, parse and execute the file and copy the result to the target
-				if err = h.TemplateEngine.ParseAndExecuteFile(path, h.Config.Object(), destinationFile); err != nil {
-					return errors.Wrapf(err, "Error processing file %v", path)
+			if scoped, ok := h.Config.(scopedConfig); ok && !info.IsDir() {
+				if other, found := generatedBy[targetPath]; found && other != scoped.scope {
+					return fmt.Errorf("'%v' renders to '%v' for both %v and %v, use the item in its name so each item gets its own file", relPath, targetPath, other, scoped.scope)
 				}
+				generatedBy[targetPath] = scoped.scope
 			}
 
-			return nil
+			return fn(h, path, relPath, targetPath, info)
 		})
 }
 
-// GetTargetPath Converts a template path into the output path
+// isIncluded evaluates the manifest rules that match the relative template path, returning false if any of them exclude it
+func (h RootHandler) isIncluded(relPath string) (bool, error) {
+	for _, rule := range h.Manifest.Rules {
+		if !manifest.Match(rule.Glob(), relPath) {
+			continue
+		}
+
+		condition := true
+		if rule.When != "" {
+			rendered, err := h.TemplateEngine.ParseAndExecutePath(rule.When, h.Config.Object())
+			if err != nil {
+				return false, errors.Wrapf(err, "Error evaluating condition for rule '%v'", rule.Glob())
+			}
+			condition = isTruthy(rendered)
+		}
+
+		if (rule.Include != "" && !condition) || (rule.Exclude != "" && condition) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// GetTargetPath Converts a template path into the output path. If any part of the path renders to an empty string an empty target path is returned,
+// meaning the path should not be created. Paths that render to an absolute path, or to somewhere outside outputPath, are an error.
 func (h RootHandler) GetTargetPath(templatePath, outputPath, path string, settings interface{}) (string, error) {
 	relPath, err := filepath.Rel(templatePath, path)
 	if err != nil {
@@ -126,14 +285,38 @@ func (h RootHandler) GetTargetPath(templatePath, outputPath, path string, settin
 	if err != nil {
 		return "", err
 	}
+	if hasEmptySegment(relTarPath) {
+		return "", nil
+	}
+	if err = h.checkTargetPath(relPath, relTarPath); err != nil {
+		return "", err
+	}
 	tarPath := filepath.Join(outputPath, relTarPath)
 	return tarPath, nil
 }
 
-func shouldBeIgnored(path string) bool {
-	if strings.Contains(path, ".git") ||
-		strings.Contains(path, ".stencil") {
-		return true
+// hasEmptySegment reports whether any directory or file name in the rendered path is blank
+func hasEmptySegment(relPath string) bool {
+	for _, segment := range strings.Split(strings.TrimPrefix(filepath.ToSlash(relPath), "/"), "/") {
+		if strings.TrimSpace(segment) == "" {
+			return true
+		}
 	}
 	return false
 }
+
+// isTruthy reports whether a rendered condition should be treated as true
+func isTruthy(rendered string) bool {
+	switch strings.ToLower(strings.TrimSpace(rendered)) {
+	case "", "false", "0", "no", "<no value>":
+		return false
+	}
+	return true
+}
+
+func skip(info os.FileInfo) error {
+	if info.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
@@ -15,6 +15,8 @@
 package handlers
 
 import (
+	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -24,6 +26,8 @@ import (
 
 	"github.com/Chris-Greaves/stencil/cmd/handlers/mocks"
 	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/Chris-Greaves/stencil/engine"
+	"github.com/Chris-Greaves/stencil/manifest"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -138,20 +142,22 @@ func TestProcessTemplateReturnsErrorsFromGetTargetPath(t *testing.T) {
 
 func TestProcessTemplateReturnsErrorsFromParseAndExecuteFile(t *testing.T) {
 	mockEngine, mockConfig, mockIO := createMocks()
+	outputPath := createTempPath(t, "test-output-folder-")
 	templatePath := createTempPath(t, "test-template-")
 	defer os.RemoveAll(templatePath)
+	defer os.RemoveAll(outputPath)
 
 	f, err := ioutil.TempFile(templatePath, "test-file-")
 	require.NoError(t, err)
 	f.Close()
 
 	mockConfig.On("Object").Return("")
-	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(f.Name(), nil)
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(filepath.Base(f.Name()), nil)
 	mockEngine.On("ParseAndExecuteFile", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("Bang!"))
 
 	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
 
-	err = handler.ProcessTemplate(templatePath, "")
+	err = handler.ProcessTemplate(templatePath, outputPath)
 	assert.Error(t, err)
 	mockEngine.AssertExpectations(t)
 }
@@ -224,6 +230,145 @@ func TestProcessTemplateWorksCorrectly(t *testing.T) {
 	assert.False(t, info.IsDir())
 }
 
+func TestProcessTemplateAppliesManifestRules(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	outputPath := createTempPath(t, "test-output-folder-")
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+	defer os.RemoveAll(outputPath)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(templatePath, "app", "docs"), 0755))
+	for _, name := range []string{"Dockerfile", filepath.Join("app", "Dockerfile"), filepath.Join("app", "main.go"), filepath.Join("app", "docs", "index.md")} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, name), []byte("template"), 0644))
+	}
+
+	mockConfig.On("Object").Return("")
+	mockEngine.On("ParseAndExecutePath", "{{ .features.docker }}", mock.Anything).Return("false", nil)
+	mockEngine.On("ParseAndExecutePath", "{{ not .features.docs }}", mock.Anything).Return("true", nil)
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(func(path string, settings interface{}) string { return path }, nil)
+	mockEngine.On("ParseAndExecuteFile", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+	handler.Manifest = manifest.Manifest{Rules: []manifest.Rule{
+		{Include: "Dockerfile", When: "{{ .features.docker }}"},
+		{Exclude: "app/docs", When: "{{ not .features.docs }}"},
+	}}
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(outputPath, "app", "main.go"))
+	assert.NoFileExists(t, filepath.Join(outputPath, "Dockerfile"))
+	assert.NoFileExists(t, filepath.Join(outputPath, "app", "Dockerfile"))
+	assert.NoDirExists(t, filepath.Join(outputPath, "app", "docs"))
+}
+
+func TestProcessTemplateSkipsPathsThatRenderEmpty(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	outputPath := createTempPath(t, "test-output-folder-")
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+	defer os.RemoveAll(outputPath)
+
+	require.NoError(t, os.Mkdir(filepath.Join(templatePath, "optional"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "optional", "file.txt"), []byte("template"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "kept.txt"), []byte("template"), 0644))
+
+	mockConfig.On("Object").Return("")
+	mockEngine.On("ParseAndExecutePath", "optional", mock.Anything).Return("", nil)
+	mockEngine.On("ParseAndExecutePath", "kept.txt", mock.Anything).Return("kept.txt", nil)
+	mockEngine.On("ParseAndExecuteFile", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(outputPath, "kept.txt"))
+	mockEngine.AssertNotCalled(t, "ParseAndExecutePath", filepath.Join("optional", "file.txt"), mock.Anything)
+}
+
+func TestGetTargetPathReturnsEmptyWhenANameRendersEmpty(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return("dir/", nil)
+
+	target, err := handler.GetTargetPath("template", "output", filepath.Join("template", "dir", "{{ if .x }}file{{ end }}"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "", target)
+}
+
+func TestPlanTemplateReportsStatusesWithoutWriting(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	outputPath := createTempPath(t, "test-output-folder-")
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+	defer os.RemoveAll(outputPath)
+
+	for _, name := range []string{"new.txt", "same.txt", "changed.txt"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, name), []byte("template"), 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outputPath, "same.txt"), []byte("rendered"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outputPath, "changed.txt"), []byte("local edits"), 0644))
+
+	mockConfig.On("Object").Return("")
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return(func(path string, settings interface{}) string { return path }, nil)
+	mockEngine.On("ParseAndExecuteFile", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(2).(io.Writer).Write([]byte("rendered"))
+	})
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+
+	plan, err := handler.PlanTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+
+	statuses := map[string]FileStatus{}
+	for _, entry := range plan {
+		statuses[entry.RelPath] = entry.Status
+	}
+	assert.Equal(t, map[string]FileStatus{"new.txt": StatusNew, "same.txt": StatusUnchanged, "changed.txt": StatusOverwritten}, statuses)
+
+	_, err = os.Stat(filepath.Join(outputPath, "new.txt"))
+	assert.True(t, os.IsNotExist(err), "PlanTemplate should not create files")
+	contents, err := ioutil.ReadFile(filepath.Join(outputPath, "changed.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "local edits", string(contents), "PlanTemplate should not modify files")
+}
+
+func TestPlanTemplateReturnsErrorsFromParseAndExecuteFile(t *testing.T) {
+	mockEngine, mockConfig, mockIO := createMocks()
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+
+	f, err := ioutil.TempFile(templatePath, "test-file-")
+	require.NoError(t, err)
+	f.Close()
+
+	mockConfig.On("Object").Return("")
+	mockEngine.On("ParseAndExecutePath", mock.Anything, mock.Anything).Return("file", nil)
+	mockEngine.On("ParseAndExecuteFile", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("Bang!"))
+
+	handler := NewRootHandler(mockConfig, mockEngine, mockIO)
+
+	_, err = handler.PlanTemplate(templatePath, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Bang!")
+}
+
+func TestPrintPlanWritesTree(t *testing.T) {
+	plan := []PlannedFile{
+		{RelPath: filepath.Join("app", "main.go"), Status: StatusOverwritten},
+		{RelPath: "app", IsDir: true, Status: StatusUnchanged},
+		{RelPath: "app-docs.md", Status: StatusNew},
+	}
+
+	var b bytes.Buffer
+	PrintPlan(&b, "out", plan)
+
+	assert.Equal(t, "out\n  app/ (unchanged)\n    main.go (overwritten)\n  app-docs.md (new)\n\n1 new, 1 overwritten, 0 unchanged\n", b.String())
+}
+
 func createMocks() (*mocks.Engine, *mocks.Config, *mocks.IOWrapper) {
 	return new(mocks.Engine), new(mocks.Config), new(mocks.IOWrapper)
 }
@@ -234,3 +379,27 @@ func createTempPath(t *testing.T, path string) string {
 	require.NoError(t, err)
 	return templatePath
 }
+
+// createHandlerTest creates an empty template and output directory, removed when the test finishes, and a handler using the configuration
+func createHandlerTest(t *testing.T, conf string) (RootHandler, string, string) {
+	templatePath := createTempPath(t, "test-template-")
+	outputPath := createTempPath(t, "test-output-folder-")
+	t.Cleanup(func() {
+		os.RemoveAll(templatePath)
+		os.RemoveAll(outputPath)
+	})
+
+	config, confDir := createConf(t, conf)
+	os.RemoveAll(confDir)
+
+	return NewRootHandler(config, engine.New(), new(mocks.IOWrapper)), templatePath, outputPath
+}
+
+// writeFiles writes each of the files, named by their slash separated path inside dir, creating the directories they are in
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// StagingPrefix starts the name of the directory the template is generated into, inside the output directory, before being moved into place
+const StagingPrefix = ".stencil-staging-"
+
+// staging holds the generated project until the whole template has been processed, so a failure part way through never leaves a
+// partial project in the output directory
+type staging struct {
+	dir string
+}
+
+func newStaging(outputPath string) (*staging, error) {
+	parent := outputPath
+	if parent == "" {
+		parent = "."
+	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, errors.Wrap(err, "Error creating output directory")
+	}
+
+	dir, err := ioutil.TempDir(parent, StagingPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating staging directory")
+	}
+	stage := &staging{dir: dir}
+	if err = os.Mkdir(stage.files(), 0755); err != nil {
+		stage.cleanup()
+		return nil, errors.Wrap(err, "Error creating staging directory")
+	}
+	return stage, nil
+}
+
+// path returns where the target path inside outputPath is staged
+func (s *staging) path(outputPath, targetPath string) (string, error) {
+	rel, err := filepath.Rel(outputPath, targetPath)
+	if err != nil {
+		return "", errors.Wrap(err, "Error getting relative path")
+	}
+	return filepath.Join(s.files(), rel), nil
+}
+
+func (s *staging) files() string {
+	return filepath.Join(s.dir, "files")
+}
+
+// commit moves everything staged into the output directory. If any move fails the output is put back as it was,
+// restoring files that had been replaced.
+func (s *staging) commit(outputPath string) error {
+	var created []string
+	backups := map[string]string{}
+
+	err := filepath.Walk(s.files(), func(staged string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.files(), staged)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(outputPath, rel)
+
+		if info.IsDir() {
+			if _, err := os.Stat(target); os.IsNotExist(err) {
+				if err = os.Mkdir(target, info.Mode()); err != nil {
+					return err
+				}
+				created = append(created, target)
+			}
+			return nil
+		}
+
+		if _, err := os.Lstat(target); err == nil {
+			backup := filepath.Join(s.dir, "backup", rel)
+			if err = os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+				return err
+			}
+			if err = os.Rename(target, backup); err != nil {
+				return err
+			}
+			backups[target] = backup
+		}
+
+		if err := os.Rename(staged, target); err != nil {
+			return err
+		}
+		created = append(created, target)
+		return nil
+	})
+	if err != nil {
+		rollback(created, backups)
+		return errors.Wrap(err, "Error moving the generated files into the output directory, it has been left as it was")
+	}
+	return nil
+}
+
+// cleanup removes the staging directory and anything left in it
+func (s *staging) cleanup() {
+	os.RemoveAll(s.dir)
+}
+
+// rollback removes the created paths, newest first, and puts the replaced files back
+func rollback(created []string, backups map[string]string) {
+	for i := len(created) - 1; i >= 0; i-- {
+		os.Remove(created[i])
+	}
+	for target, backup := range backups {
+		os.Rename(backup, target)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTemplateLeavesOutputAsItWasOnError(t *testing.T) {
+	handler, templatePath, outputPath := createHandlerTest(t, `{}`)
+
+	require.NoError(t, os.Mkdir(filepath.Join(templatePath, "dir"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "a.txt"), []byte("template\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "dir", "b.txt"), []byte("b\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "z.txt"), []byte("{{ if }}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outputPath, "a.txt"), []byte("existing\n"), 0644))
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.Error(t, err)
+
+	entries, err := ioutil.ReadDir(outputPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a.txt", entries[0].Name())
+	assertContents(t, filepath.Join(outputPath, "a.txt"), "existing\n")
+}
+
+func TestProcessTemplateRemovesStagingDirectory(t *testing.T) {
+	handler, templatePath, outputPath := createConflictTest(t)
+
+	err := handler.ProcessTemplate(templatePath, outputPath)
+	require.NoError(t, err)
+
+	staged, err := filepath.Glob(filepath.Join(outputPath, StagingPrefix+"*"))
+	require.NoError(t, err)
+	assert.Empty(t, staged)
+	assertContents(t, filepath.Join(outputPath, "edited.txt"), "template\n")
+}
+
+func TestStagingCommitRestoresReplacedFilesWhenAMoveFails(t *testing.T) {
+	outputPath := createTempPath(t, "test-output-folder-")
+	defer os.RemoveAll(outputPath)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outputPath, "a.txt"), []byte("existing\n"), 0644))
+	// A file where the template has a directory, so moving the file inside it fails
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outputPath, "dir"), []byte("file\n"), 0644))
+
+	stage, err := newStaging(outputPath)
+	require.NoError(t, err)
+	defer stage.cleanup()
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(stage.files(), "a.txt"), []byte("template\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(stage.files(), "dir"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(stage.files(), "dir", "b.txt"), []byte("b\n"), 0644))
+
+	err = stage.commit(outputPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "it has been left as it was")
+
+	assertContents(t, filepath.Join(outputPath, "a.txt"), "existing\n")
+	assertContents(t, filepath.Join(outputPath, "dir"), "file\n")
+}
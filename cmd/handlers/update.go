@@ -0,0 +1,218 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// NewFileSuffix is added to the name of the template's version of a binary file that can't be merged with local changes
+const NewFileSuffix = ".stencil-new"
+
+// UpdateStatus describes what updating a project did to a file
+type UpdateStatus int
+
+const (
+	// UpdateAdded means the file is new in the template and was created
+	UpdateAdded UpdateStatus = iota
+	// UpdateUpdated means the file hadn't been changed locally and was replaced with the template's new version
+	UpdateUpdated
+	// UpdateMerged means the local changes and the template's changes were merged without conflicts
+	UpdateMerged
+	// UpdateConflict means the local changes and the template's changes overlap, and conflict markers were left in the file
+	UpdateConflict
+	// UpdateRemoved means the file was removed from the template and hadn't been changed locally, so it was deleted
+	UpdateRemoved
+	// UpdateKept means the template changed or removed the file, but the local version was kept because it was changed or deleted locally
+	UpdateKept
+)
+
+func (s UpdateStatus) String() string {
+	switch s {
+	case UpdateAdded:
+		return "added"
+	case UpdateUpdated:
+		return "updated"
+	case UpdateMerged:
+		return "merged"
+	case UpdateConflict:
+		return "conflict"
+	case UpdateRemoved:
+		return "removed"
+	case UpdateKept:
+		return "kept"
+	default:
+		return "unknown"
+	}
+}
+
+// RenderedFile is a template file rendered in memory
+type RenderedFile struct {
+	Contents []byte
+	Mode     os.FileMode
+}
+
+// UpdatedFile is a file changed, or deliberately left alone, by UpdateProject
+type UpdatedFile struct {
+	RelPath string
+	Status  UpdateStatus
+}
+
+// RenderFiles renders every file in the template in memory, keyed by its target path relative to outputPath
+func (h RootHandler) RenderFiles(templatePath, outputPath string) (map[string]RenderedFile, error) {
+	files := map[string]RenderedFile{}
+
+	err := h.walkTemplate(templatePath, outputPath,
+		func(h RootHandler, path, relPath, targetPath string, info os.FileInfo) error {
+			if info.IsDir() {
+				return nil
+			}
+
+			relTarget, err := filepath.Rel(outputPath, targetPath)
+			if err != nil {
+				return errors.Wrap(err, "Error getting relative path")
+			}
+
+			buf := new(bytes.Buffer)
+			if err = h.writeFile(path, relPath, buf); err != nil {
+				return err
+			}
+			files[relTarget] = RenderedFile{Contents: buf.Bytes(), Mode: info.Mode()}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// UpdateProject brings the project up to date with the updated template, using base, the template the project was generated from,
+// to tell the local changes apart from the template's. Files only changed by the template are replaced, files changed on both sides are
+// merged, leaving conflict markers where the changes overlap, and files changed only locally are left alone.
+func UpdateProject(projectPath string, base, updated map[string]RenderedFile) ([]UpdatedFile, error) {
+	paths := map[string]bool{}
+	for relPath := range base {
+		paths[relPath] = true
+	}
+	for relPath := range updated {
+		paths[relPath] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for relPath := range paths {
+		sorted = append(sorted, relPath)
+	}
+	sort.Strings(sorted)
+
+	stage, err := newStaging(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	defer stage.cleanup()
+
+	var results []UpdatedFile
+	var removed []string
+	write := func(relPath string, contents []byte, mode os.FileMode) error {
+		staged := filepath.Join(stage.files(), relPath)
+		if err := os.MkdirAll(filepath.Dir(staged), 0755); err != nil {
+			return errors.Wrapf(err, "Error making directory %v", filepath.Dir(relPath))
+		}
+		return errors.Wrapf(ioutil.WriteFile(staged, contents, mode), "Error writing file at '%v'", relPath)
+	}
+
+	for _, relPath := range sorted {
+		targetPath := filepath.Join(projectPath, relPath)
+		previous, inBase := base[relPath]
+		next, inUpdated := updated[relPath]
+
+		current, err := ioutil.ReadFile(targetPath)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "Error reading existing file '%v'", targetPath)
+		}
+
+		var status UpdateStatus
+		var contents []byte
+		switch {
+		case !inUpdated:
+			if !exists {
+				continue
+			}
+			if !bytes.Equal(current, previous.Contents) {
+				results = append(results, UpdatedFile{RelPath: relPath, Status: UpdateKept})
+				continue
+			}
+			removed = append(removed, targetPath)
+			results = append(results, UpdatedFile{RelPath: relPath, Status: UpdateRemoved})
+			continue
+		case !exists:
+			if inBase {
+				if !bytes.Equal(previous.Contents, next.Contents) {
+					results = append(results, UpdatedFile{RelPath: relPath, Status: UpdateKept})
+				}
+				continue
+			}
+			status, contents = UpdateAdded, next.Contents
+		case bytes.Equal(current, next.Contents), inBase && bytes.Equal(previous.Contents, next.Contents):
+			// Already up to date, or only changed locally
+			continue
+		case inBase && bytes.Equal(current, previous.Contents):
+			status, contents = UpdateUpdated, next.Contents
+		case isBinary(current) || isBinary(next.Contents) || isBinary(previous.Contents):
+			// Binary files can't be merged, so the template's version is put alongside the local one
+			if err = write(relPath+NewFileSuffix, next.Contents, next.Mode); err != nil {
+				return nil, err
+			}
+			results = append(results, UpdatedFile{RelPath: relPath, Status: UpdateConflict})
+			continue
+		default:
+			merged, conflicted := mergeThreeWay(string(previous.Contents), string(current), string(next.Contents))
+			status, contents = UpdateMerged, merged
+			if conflicted {
+				status = UpdateConflict
+			}
+		}
+
+		mode := next.Mode
+		if info, err := os.Stat(targetPath); err == nil {
+			mode = info.Mode()
+		}
+		if err = write(relPath, contents, mode); err != nil {
+			return nil, err
+		}
+		results = append(results, UpdatedFile{RelPath: relPath, Status: status})
+	}
+
+	if err = stage.commit(projectPath); err != nil {
+		return nil, err
+	}
+	for _, targetPath := range removed {
+		if err = os.Remove(targetPath); err != nil {
+			return nil, errors.Wrapf(err, "Error removing '%v'", targetPath)
+		}
+	}
+
+	return results, nil
+}
+
+func isBinary(contents []byte) bool {
+	return bytes.IndexByte(contents, 0) >= 0
+}
@@ -0,0 +1,118 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers/mocks"
+	"github.com/Chris-Greaves/stencil/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderFilesRendersEveryFileInMemory(t *testing.T) {
+	templatePath := createTempPath(t, "test-template-")
+	outputPath := createTempPath(t, "test-output-folder-")
+	defer os.RemoveAll(templatePath)
+	defer os.RemoveAll(outputPath)
+
+	require.NoError(t, os.Mkdir(filepath.Join(templatePath, "{{ .name }}"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "{{ .name }}", "main.go"), []byte("package {{ .name }}\n"), 0644))
+
+	conf, confDir := createConf(t, `{"name": "svc"}`)
+	defer os.RemoveAll(confDir)
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+
+	files, err := handler.RenderFiles(templatePath, outputPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]RenderedFile{
+		filepath.Join("svc", "main.go"): {Contents: []byte("package svc\n"), Mode: 0644},
+	}, files)
+	entries, err := ioutil.ReadDir(outputPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestUpdateProjectMergesTemplateChangesWithLocalChanges(t *testing.T) {
+	projectPath := createTempPath(t, "test-output-folder-")
+	defer os.RemoveAll(projectPath)
+
+	rendered := func(contents string) RenderedFile {
+		return RenderedFile{Contents: []byte(contents), Mode: 0644}
+	}
+	base := map[string]RenderedFile{
+		"untouched.txt":   rendered("v1\n"),
+		"local.txt":       rendered("v1\n"),
+		"both.txt":        rendered("a\nb\nc\n"),
+		"clash.txt":       rendered("a\nb\nc\n"),
+		"gone.txt":        rendered("old\n"),
+		"edited-gone.txt": rendered("old\n"),
+		"deleted.txt":     rendered("v1\n"),
+		"binary.bin":      rendered("\x00v1"),
+	}
+	updated := map[string]RenderedFile{
+		"untouched.txt": rendered("v2\n"),
+		"local.txt":     rendered("v1\n"),
+		"both.txt":      rendered("a\nb\nC\n"),
+		"clash.txt":     rendered("a\ntemplate\nc\n"),
+		"deleted.txt":   rendered("v2\n"),
+		"binary.bin":    rendered("\x00v2"),
+		"dir/new.txt":   rendered("new\n"),
+	}
+	writeFiles(t, projectPath, map[string]string{
+		"untouched.txt":   "v1\n",
+		"local.txt":       "local\n",
+		"both.txt":        "A\nb\nc\n",
+		"clash.txt":       "a\nlocal\nc\n",
+		"gone.txt":        "old\n",
+		"edited-gone.txt": "edited\n",
+		"binary.bin":      "\x00local",
+	})
+
+	results, err := UpdateProject(projectPath, base, updated)
+	require.NoError(t, err)
+
+	assert.Equal(t, []UpdatedFile{
+		{RelPath: "binary.bin", Status: UpdateConflict},
+		{RelPath: "both.txt", Status: UpdateMerged},
+		{RelPath: "clash.txt", Status: UpdateConflict},
+		{RelPath: "deleted.txt", Status: UpdateKept},
+		{RelPath: filepath.Join("dir", "new.txt"), Status: UpdateAdded},
+		{RelPath: "edited-gone.txt", Status: UpdateKept},
+		{RelPath: "gone.txt", Status: UpdateRemoved},
+		{RelPath: "untouched.txt", Status: UpdateUpdated},
+	}, results)
+
+	assertContents(t, filepath.Join(projectPath, "untouched.txt"), "v2\n")
+	assertContents(t, filepath.Join(projectPath, "local.txt"), "local\n")
+	assertContents(t, filepath.Join(projectPath, "both.txt"), "A\nb\nC\n")
+	assertContents(t, filepath.Join(projectPath, "clash.txt"),
+		"a\n<<<<<<< existing\nlocal\n||||||| base\nb\n=======\ntemplate\n>>>>>>> template\nc\n")
+	assertContents(t, filepath.Join(projectPath, "edited-gone.txt"), "edited\n")
+	assertContents(t, filepath.Join(projectPath, "binary.bin"), "\x00local")
+	assertContents(t, filepath.Join(projectPath, "binary.bin"+NewFileSuffix), "\x00v2")
+	assertContents(t, filepath.Join(projectPath, "dir", "new.txt"), "new\n")
+	assert.False(t, fileExists(filepath.Join(projectPath, "gone.txt")))
+	assert.False(t, fileExists(filepath.Join(projectPath, "deleted.txt")))
+
+	staged, err := filepath.Glob(filepath.Join(projectPath, StagingPrefix+"*"))
+	require.NoError(t, err)
+	assert.Empty(t, staged)
+}
@@ -0,0 +1,139 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Chris-Greaves/stencil/engine"
+	"github.com/Chris-Greaves/stencil/manifest"
+)
+
+// ManifestPath is where problems in the manifest are reported, relative to the template
+var ManifestPath = filepath.Join(".stencil", "manifest.json")
+
+// TemplateProblem is an error found while validating the template. Part is empty for the contents of a file, otherwise it says which
+// part of Path failed, e.g. "name" for a file or directory name, or the rule or hook in the manifest.
+type TemplateProblem struct {
+	Path    string
+	Part    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p TemplateProblem) String() string {
+	location := filepath.ToSlash(p.Path)
+	if p.Part != "" {
+		location += " (" + p.Part + ")"
+	}
+	if p.Line > 0 {
+		location += fmt.Sprintf(":%v", p.Line)
+	}
+	if p.Column > 0 {
+		location += fmt.Sprintf(":%v", p.Column)
+	}
+	return location + ": " + p.Message
+}
+
+// ValidationError lists every problem found in the template
+type ValidationError struct {
+	Problems []TemplateProblem
+}
+
+func (e ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = problem.String()
+	}
+	return fmt.Sprintf("%v problems found in the template:\n  %v", len(e.Problems), strings.Join(lines, "\n  "))
+}
+
+// ValidateTemplate parses and executes every rule, hook, path and file in the template using the current configuration, including every
+// item of repeated paths, without writing anything, and returns a ValidationError listing all of the problems rather than stopping at the first one
+func (h RootHandler) ValidateTemplate(templatePath string) error {
+	var problems []TemplateProblem
+	record := func(path, part string, err error) {
+		problem := TemplateProblem{Path: path, Part: part, Message: err.Error()}
+		if templateErr, ok := engine.ParseTemplateError(err); ok {
+			problem.Line, problem.Column, problem.Message = templateErr.Line, templateErr.Column, templateErr.Message
+		}
+		// Repeated paths fail the same way for every item, so each problem is only reported once
+		for _, existing := range problems {
+			if existing == problem {
+				return
+			}
+		}
+		problems = append(problems, problem)
+	}
+
+	for _, stage := range []HookStage{PreHooks, PostHooks} {
+		for i, command := range h.hooksFor(stage) {
+			if _, err := h.TemplateEngine.ParseAndExecutePath(command, h.Config.Object()); err != nil {
+				record(ManifestPath, fmt.Sprintf("%v hook %v", stage, i+1), err)
+			}
+		}
+	}
+
+	ignore, err := h.loadIgnore(templatePath)
+	if err != nil {
+		return err
+	}
+
+	err = h.walkPaths(templatePath, templatePath, ignore, record,
+		func(h RootHandler, path, relPath string, info os.FileInfo) error {
+			// Rules are checked against the paths they apply to, as their conditions may use the item of a repeat
+			for _, rule := range h.Manifest.Rules {
+				if rule.When == "" || !manifest.Match(rule.Glob(), relPath) {
+					continue
+				}
+				if _, err := h.TemplateEngine.ParseAndExecutePath(rule.When, h.Config.Object()); err != nil {
+					record(ManifestPath, fmt.Sprintf("rule '%v'", rule.Glob()), err)
+				}
+			}
+			// Failing rules have already been reported, so keep validating the paths they match
+			if included, err := h.isIncluded(relPath); err == nil && !included {
+				return skip(info)
+			}
+
+			// Each name is rendered on its own, so a broken directory name is reported once rather than for everything inside it
+			name, err := h.TemplateEngine.ParseAndExecutePath(info.Name(), h.Config.Object())
+			if err != nil {
+				record(relPath, "name", err)
+			} else if strings.TrimSpace(name) == "" {
+				return skip(info)
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+			if err = h.writeFile(path, relPath, ioutil.Discard); err != nil {
+				record(relPath, "", err)
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers/mocks"
+	"github.com/Chris-Greaves/stencil/engine"
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTemplateReportsEveryProblem(t *testing.T) {
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+
+	writeFiles(t, templatePath, map[string]string{
+		"ok.txt":                 "{{ .name }}\n",
+		"first.txt":              "line one\n{{ if }}\n",
+		"second.txt":             "{{ .name }} {{ .name.missing }}\n",
+		"{{ .bad- }}/inside.txt": "{{ unknown }}",
+	})
+
+	conf, confDir := createConf(t, `{"name": "stencil"}`)
+	defer os.RemoveAll(confDir)
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+	handler.Manifest = manifest.Manifest{
+		Rules: []manifest.Rule{{Include: "ok.txt", When: "{{ .name | nope }}"}},
+	}
+
+	err := handler.ValidateTemplate(templatePath)
+	require.Error(t, err)
+	require.IsType(t, ValidationError{}, err)
+
+	problems := err.(ValidationError).Problems
+	require.Len(t, problems, 5)
+	assert.Equal(t, TemplateProblem{Path: "first.txt", Line: 2, Message: "missing value for if"}, problems[0])
+	assert.Equal(t, TemplateProblem{Path: ManifestPath, Part: "rule 'ok.txt'", Line: 1, Message: `function "nope" not defined`}, problems[1])
+	assert.Equal(t, "second.txt", problems[2].Path)
+	assert.Equal(t, 1, problems[2].Line)
+	assert.Equal(t, 20, problems[2].Column)
+	assert.Equal(t, "{{ .bad- }}", problems[3].Path)
+	assert.Equal(t, "name", problems[3].Part)
+	assert.Equal(t, filepath.Join("{{ .bad- }}", "inside.txt"), problems[4].Path)
+
+	assert.Contains(t, err.Error(), "5 problems found in the template:\n")
+	assert.Contains(t, err.Error(), "\n  first.txt:2: missing value for if")
+	assert.Contains(t, err.Error(), "\n  {{ .bad- }} (name):1: ")
+}
+
+func TestValidateTemplateSkipsExcludedPaths(t *testing.T) {
+	templatePath := createTempPath(t, "test-template-")
+	defer os.RemoveAll(templatePath)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "excluded.txt"), []byte("{{ if }}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "{{ if .docker }}Dockerfile{{ end }}"), []byte("{{ if }}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templatePath, "kept.txt"), []byte("{{ .name }}"), 0644))
+
+	conf, confDir := createConf(t, `{"name": "stencil", "docker": false}`)
+	defer os.RemoveAll(confDir)
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+	handler.Manifest = manifest.Manifest{Rules: []manifest.Rule{{Exclude: "excluded.txt"}}}
+
+	assert.NoError(t, handler.ValidateTemplate(templatePath))
+}
@@ -0,0 +1,339 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/pkg/errors"
+)
+
+// valueNode is a value in the configuration, or a variable declared in the manifest, whose default may refer to other values
+type valueNode struct {
+	name     string
+	variable manifest.Variable
+	declared bool
+	// provided values are used as they are, even if they look like a template
+	provided bool
+	// value is the current value, or the variable's default when there isn't one
+	value interface{}
+	// deps are the values the default refers to
+	deps []*valueNode
+}
+
+// ApplyDefaults sets every value to its default without asking the user, rendering computed defaults and returning an error
+// if any variable is invalid
+func (h RootHandler) ApplyDefaults() error {
+	return h.resolveValues(false)
+}
+
+// resolveValues works out every value in dependency order, rendering defaults that are templates once the values they refer to are known.
+// Values that aren't declared in the manifest are offered to the user in batches, only breaking the batch when a later default needs its answers.
+func (h RootHandler) resolveValues(ask bool) error {
+	nodes, err := h.valueNodes()
+	if err != nil {
+		return err
+	}
+	ordered, err := h.orderValues(nodes)
+	if err != nil {
+		return err
+	}
+
+	var batch []confighelper.Setting
+	inBatch := map[*valueNode]bool{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		updatedSets, err := h.IO.GetOverrides(batch)
+		if err != nil {
+			return err
+		}
+		batch, inBatch = nil, map[*valueNode]bool{}
+		return h.Config.SetValues(updatedSets)
+	}
+
+	for _, node := range ordered {
+		if node.declared || dependsOnAny(node, inBatch) {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+
+		value, rendered, err := h.renderDefault(node)
+		if err != nil {
+			return err
+		}
+
+		// Values provided with --set or --values have already been chosen, so they're only checked rather than asked for again
+		askNode := ask && !node.provided
+		if node.declared {
+			if err = h.resolveVariable(node.variable, value, askNode); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if rendered {
+			if err = h.Config.SetValues([]confighelper.Setting{{Name: node.name, Value: value}}); err != nil {
+				return err
+			}
+		}
+		if askNode {
+			batch = append(batch, confighelper.Setting{Name: node.name, Value: value})
+			inBatch[node] = true
+		}
+	}
+
+	return flush()
+}
+
+// resolveVariable asks the user for the variable when ask is set, then validates and stores its value
+func (h RootHandler) resolveVariable(variable manifest.Variable, value interface{}, ask bool) error {
+	value, err := convertValue(variable, value)
+	if err != nil {
+		return err
+	}
+
+	if ask {
+		if value, err = h.IO.Prompt(variable, value); err != nil {
+			return err
+		}
+	}
+
+	if err = variable.Validate(value); err != nil {
+		return fmt.Errorf("Invalid value for '%v'. Error: %v", variable.Name, err.Error())
+	}
+
+	return h.Config.SetValues([]confighelper.Setting{{Name: variable.Name, Value: value}})
+}
+
+// valueNodes lists the variables declared in the manifest, in order, followed by the rest of the configuration ordered by name
+func (h RootHandler) valueNodes() ([]*valueNode, error) {
+	settings, err := h.Config.GetAllValues()
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]interface{}{}
+	for _, setting := range settings {
+		current[setting.Name] = setting.Value
+	}
+
+	var nodes []*valueNode
+	for _, variable := range h.Manifest.Variables {
+		value, found := current[variable.Name]
+		if !found {
+			value = variable.Default
+		}
+		nodes = append(nodes, &valueNode{name: variable.Name, variable: variable, declared: true, value: value, provided: h.Provided[variable.Name]})
+		delete(current, variable.Name)
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		nodes = append(nodes, &valueNode{name: name, value: current[name], provided: h.Provided[name]})
+	}
+
+	return nodes, nil
+}
+
+// orderValues sorts the values so each comes after the values its default refers to, otherwise keeping their order.
+// Defaults that refer to each other in a cycle are an error naming the values involved.
+func (h RootHandler) orderValues(nodes []*valueNode) ([]*valueNode, error) {
+	left, right := h.delimiters()
+	for _, node := range nodes {
+		text, ok := node.value.(string)
+		if !ok || node.provided || !strings.Contains(text, left) {
+			continue
+		}
+
+		refs, err := templateReferences(text, left, right)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error reading the default of '%v'", node.name)
+		}
+		for _, other := range nodes {
+			for _, ref := range refs {
+				if refersTo(ref, other.name) {
+					node.deps = append(node.deps, other)
+					break
+				}
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[*valueNode]int{}
+	var ordered []*valueNode
+	var path []string
+
+	var visit func(node *valueNode) error
+	visit = func(node *valueNode) error {
+		switch state[node] {
+		case visited:
+			return nil
+		case visiting:
+			for i, name := range path {
+				if name == node.name {
+					return fmt.Errorf("Defaults refer to each other in a cycle: %v", strings.Join(append(path[i:], node.name), " -> "))
+				}
+			}
+		}
+
+		state[node] = visiting
+		path = append(path, node.name)
+		for _, dep := range node.deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = visited
+
+		ordered = append(ordered, node)
+		return nil
+	}
+
+	for _, node := range nodes {
+		if err := visit(node); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// renderDefault renders the value when it is a template, reporting whether it was. Provided values are never rendered.
+func (h RootHandler) renderDefault(node *valueNode) (interface{}, bool, error) {
+	left, _ := h.delimiters()
+	text, ok := node.value.(string)
+	if !ok || node.provided || !strings.Contains(text, left) {
+		return node.value, false, nil
+	}
+
+	rendered, err := h.TemplateEngine.ParseAndExecutePath(text, h.Config.Object())
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "Error rendering the default of '%v'", node.name)
+	}
+	return rendered, true, nil
+}
+
+func (h RootHandler) delimiters() (string, string) {
+	if h.Manifest.Delimiters.Left != "" {
+		return h.Manifest.Delimiters.Left, h.Manifest.Delimiters.Right
+	}
+	return "{{", "}}"
+}
+
+// convertValue converts text, such as a rendered default or a value given with --set, into the variable's type
+func convertValue(variable manifest.Variable, value interface{}) (interface{}, error) {
+	text, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	converted, err := confighelper.ParseValue(text, variable.Like(nil))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid value for '%v'. Error: %v", variable.Name, err.Error())
+	}
+	return converted, nil
+}
+
+func dependsOnAny(node *valueNode, nodes map[*valueNode]bool) bool {
+	for _, dep := range node.deps {
+		if nodes[dep] {
+			return true
+		}
+	}
+	return false
+}
+
+// refersTo reports whether a reference such as "project" or "project.name.length" uses the value with the name
+func refersTo(ref, name string) bool {
+	return ref == name || strings.HasPrefix(name, ref+".") || strings.HasPrefix(ref, name+".")
+}
+
+// templateReferences finds the values a template refers to, as dotted names. Fields inside "range" and "with" are relative to
+// their pipeline rather than the configuration, so only references through "$" are found there.
+func templateReferences(text, left, right string) ([]string, error) {
+	tree := parse.New("default")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, left, right, map[string]*parse.Tree{}); err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	var walk func(node parse.Node, rooted bool)
+	walk = func(node parse.Node, rooted bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child, rooted)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, rooted)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd, rooted)
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, rooted)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, rooted)
+		case *parse.FieldNode:
+			if rooted {
+				refs = append(refs, strings.Join(n.Ident, "."))
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				refs = append(refs, strings.Join(n.Ident[1:], "."))
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, rooted)
+			walk(n.List, rooted)
+			walk(n.ElseList, rooted)
+		case *parse.RangeNode:
+			walk(n.Pipe, rooted)
+			walk(n.List, false)
+			walk(n.ElseList, rooted)
+		case *parse.WithNode:
+			walk(n.Pipe, rooted)
+			walk(n.List, false)
+			walk(n.ElseList, rooted)
+		case *parse.TemplateNode:
+			walk(n.Pipe, rooted)
+		}
+	}
+	walk(tree.Root, true)
+
+	return refs, nil
+}
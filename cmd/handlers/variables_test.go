@@ -0,0 +1,232 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers/mocks"
+	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/Chris-Greaves/stencil/engine"
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfferConfigOverridesAsksForVariablesInOrder(t *testing.T) {
+	conf, dir := createConf(t, `{ "project": { "name": "app" }, "other": "value" }`)
+	defer os.RemoveAll(dir)
+	mockIO := new(mocks.IOWrapper)
+
+	name := manifest.Variable{Name: "project.name", Label: "Project name", Required: true}
+	module := manifest.Variable{Name: "project.module", Default: "github.com/acme/{{ .project.name }}"}
+
+	mockIO.On("Prompt", name, "app").Return("svc", nil).Once()
+	mockIO.On("Prompt", module, "github.com/acme/svc").Return("github.com/acme/svc", nil).Once()
+	mockIO.On("GetOverrides", []confighelper.Setting{{Name: "other", Value: "value"}}).Return(nil, nil)
+
+	handler := NewRootHandler(conf, engine.New(), mockIO)
+	handler.Manifest = manifest.Manifest{Variables: []manifest.Variable{name, module}}
+
+	err := handler.OfferConfigOverrides()
+	require.NoError(t, err)
+	mockIO.AssertExpectations(t)
+
+	assert.Equal(t, map[string]interface{}{
+		"project": map[string]interface{}{"name": "svc", "module": "github.com/acme/svc"},
+		"other":   "value",
+	}, conf.Object())
+}
+
+func TestOfferConfigOverridesReturnsErrorForInvalidAnswer(t *testing.T) {
+	conf, dir := createConf(t, `{}`)
+	defer os.RemoveAll(dir)
+	mockIO := new(mocks.IOWrapper)
+
+	license := manifest.Variable{Name: "license", Choices: []interface{}{"MIT", "Apache-2.0"}}
+	mockIO.On("Prompt", license, mock.Anything).Return("GPL", nil)
+
+	handler := NewRootHandler(conf, engine.New(), mockIO)
+	handler.Manifest = manifest.Manifest{Variables: []manifest.Variable{license}}
+
+	err := handler.OfferConfigOverrides()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid value for 'license'")
+}
+
+func TestOfferConfigOverridesDoesNotAskForProvidedValues(t *testing.T) {
+	conf, dir := createConf(t, `{}`)
+	defer os.RemoveAll(dir)
+	mockIO := new(mocks.IOWrapper)
+
+	handler := NewRootHandler(conf, engine.New(), mockIO)
+	handler.Manifest = manifest.Manifest{Variables: []manifest.Variable{
+		{Name: "license", Choices: []interface{}{"MIT", "Apache-2.0"}},
+		{Name: "replicas", Type: manifest.TypeNumber},
+	}}
+	handler, err := handler.ProvideValues([]confighelper.Setting{
+		{Name: "license", Value: "MIT"},
+		{Name: "replicas", Value: "3"},
+		{Name: "other", Value: "value"},
+	})
+	require.NoError(t, err)
+
+	err = handler.OfferConfigOverrides()
+	require.NoError(t, err)
+	mockIO.AssertNotCalled(t, "Prompt", mock.Anything, mock.Anything)
+	mockIO.AssertNotCalled(t, "GetOverrides", mock.Anything)
+	assert.Equal(t, map[string]interface{}{"license": "MIT", "replicas": 3.0, "other": "value"}, conf.Object())
+}
+
+func TestOfferConfigOverridesValidatesProvidedValues(t *testing.T) {
+	conf, dir := createConf(t, `{}`)
+	defer os.RemoveAll(dir)
+
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+	handler.Manifest = manifest.Manifest{Variables: []manifest.Variable{{Name: "license", Choices: []interface{}{"MIT", "Apache-2.0"}}}}
+	handler, err := handler.ProvideValues([]confighelper.Setting{{Name: "license", Value: "GPL"}})
+	require.NoError(t, err)
+
+	err = handler.OfferConfigOverrides()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid value for 'license'")
+}
+
+func TestApplyDefaultsConvertsValuesToVariableType(t *testing.T) {
+	conf, dir := createConf(t, `{}`)
+	defer os.RemoveAll(dir)
+	require.NoError(t, conf.SetValues([]confighelper.Setting{{Name: "replicas", Value: "3"}}))
+
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+	handler.Manifest = manifest.Manifest{Variables: []manifest.Variable{
+		{Name: "replicas", Type: manifest.TypeNumber, Default: 1.0},
+		{Name: "docker", Type: manifest.TypeBool, Default: "{{ gt .replicas 1.0 }}"},
+		{Name: "ports", Type: manifest.TypeList, Default: "80, 443"},
+	}}
+
+	err := handler.ApplyDefaults()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"replicas": 3.0,
+		"docker":   true,
+		"ports":    []interface{}{"80", "443"},
+	}, conf.Object())
+}
+
+func TestApplyDefaultsErrorsWhenRequiredValueIsMissing(t *testing.T) {
+	conf, dir := createConf(t, `{}`)
+	defer os.RemoveAll(dir)
+
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+	handler.Manifest = manifest.Manifest{Variables: []manifest.Variable{{Name: "project.owner", Required: true}}}
+
+	err := handler.ApplyDefaults()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a value is required")
+}
+
+func TestApplyDefaultsKeepsProvidedValuesVerbatim(t *testing.T) {
+	conf, dir := createConf(t, `{ "expr": "", "greeting": "Hello {{ .name }}", "name": "app" }`)
+	defer os.RemoveAll(dir)
+
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+	handler.Manifest = manifest.Manifest{Variables: []manifest.Variable{{Name: "token", Default: "{{ .name }}"}}}
+	handler, err := handler.ProvideValues([]confighelper.Setting{
+		{Name: "expr", Value: "${{ secrets.TOKEN }}"},
+		{Name: "token", Value: "{{ .name }}"},
+	})
+	require.NoError(t, err)
+
+	err = handler.ApplyDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"expr":     "${{ secrets.TOKEN }}",
+		"greeting": "Hello app",
+		"name":     "app",
+		"token":    "{{ .name }}",
+	}, conf.Object())
+}
+
+func createConf(t *testing.T, contents string) (*confighelper.Conf, string) {
+	dir := createTempPath(t, "test-conf-")
+
+	path := filepath.Join(dir, ".stencil.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+	conf, err := confighelper.New(path)
+	require.NoError(t, err)
+	return conf, dir
+}
+
+func TestApplyDefaultsRendersComputedDefaultsInDependencyOrder(t *testing.T) {
+	conf, dir := createConf(t, `{
+		"module_path": "github.com/{{ .org }}/{{ .project.name }}",
+		"org": "{{ .owner }}",
+		"owner": "acme",
+		"project": { "name": "svc" }
+	}`)
+	defer os.RemoveAll(dir)
+
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+
+	err := handler.ApplyDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/acme/svc", conf.Object().(map[string]interface{})["module_path"])
+}
+
+func TestApplyDefaultsErrorsOnCycle(t *testing.T) {
+	conf, dir := createConf(t, `{ "a": "{{ .b }}", "b": "{{ .c }}", "c": "x{{ .a }}", "d": "{{ .d }}" }`)
+	defer os.RemoveAll(dir)
+
+	handler := NewRootHandler(conf, engine.New(), new(mocks.IOWrapper))
+
+	err := handler.ApplyDefaults()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a -> b -> c -> a")
+}
+
+func TestOfferConfigOverridesAsksForValuesBeforeDefaultsUsingThem(t *testing.T) {
+	conf, dir := createConf(t, `{ "org": "acme", "module_path": "github.com/{{ .org }}/app" }`)
+	defer os.RemoveAll(dir)
+	mockIO := new(mocks.IOWrapper)
+
+	mockIO.On("GetOverrides", []confighelper.Setting{{Name: "org", Value: "acme"}}).
+		Return([]confighelper.Setting{{Name: "org", Value: "example"}}, nil).Once()
+	mockIO.On("GetOverrides", []confighelper.Setting{{Name: "module_path", Value: "github.com/example/app"}}).
+		Return(nil, nil).Once()
+
+	handler := NewRootHandler(conf, engine.New(), mockIO)
+
+	err := handler.OfferConfigOverrides()
+	require.NoError(t, err)
+	mockIO.AssertExpectations(t)
+	assert.Equal(t, "github.com/example/app", conf.Object().(map[string]interface{})["module_path"])
+}
+
+func TestTemplateReferences(t *testing.T) {
+	refs, err := templateReferences(
+		`{{ .a.b }} {{ range .items }}{{ .name }}{{ $.c }}{{ end }} {{ if .d }}{{ upper .e }}{{ end }} <% .f %>`, "{{", "}}")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.b", "items", "c", "d", "e"}, refs)
+
+	refs, err = templateReferences(`{{ .a }} <% .f %>`, "<%", "%>")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"f"}, refs)
+}
@@ -0,0 +1,107 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputDir               string
+	ErrOutputInsideTemplate = errors.New("The output directory can't be inside the template")
+	ErrOutputNotEmpty       = errors.New("The output directory isn't empty, use --overwrite, --skip-existing, --fail-on-conflict or --ask-on-conflict to write into it anyway")
+)
+
+func init() {
+	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "", "directory to create the project in, created if missing (default is the current directory)")
+}
+
+// outputDirectory returns the absolute path of --output, or the working directory if it wasn't given
+func outputDirectory() (string, error) {
+	if outputDir == "" {
+		return os.Getwd()
+	}
+	return filepath.Abs(outputDir)
+}
+
+// prepareOutputDirectory checks the output directory can be used with the template, then creates it unless this is a dry run,
+// reporting whether it had to be created. An explicit --output that already has files in it needs a conflict policy to be chosen,
+// except for a dry run, whose plan shows what would happen to each existing file.
+func prepareOutputDirectory(cmd *cobra.Command, templatePath, outputPath string) (bool, error) {
+	if handlers.IsWithin(outputPath, templatePath) {
+		return false, ErrOutputInsideTemplate
+	}
+
+	if cmd.Flags().Changed("output") && !conflictPolicyChosen(cmd) && !dryRun {
+		empty, err := isEmptyDir(outputPath)
+		if err != nil {
+			return false, err
+		}
+		if !empty {
+			return false, ErrOutputNotEmpty
+		}
+	}
+
+	if dryRun {
+		return false, nil
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return false, errors.Wrap(err, "Error creating output directory")
+	}
+	return true, nil
+}
+
+// removeIfCreated removes an output directory this run created if the project was never generated in it, so a failed run
+// doesn't leave an empty or half made directory behind
+func removeIfCreated(outputPath string, created bool, generated *bool) {
+	if created && !*generated {
+		os.RemoveAll(outputPath)
+	}
+}
+
+func conflictPolicyChosen(cmd *cobra.Command) bool {
+	for _, flag := range []string{"overwrite", "skip-existing", "fail-on-conflict", "ask-on-conflict"} {
+		if cmd.Flags().Changed(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyDir reports whether the directory has no entries. A directory that doesn't exist yet is empty.
+func isEmptyDir(path string) (bool, error) {
+	dir, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "Error reading output directory")
+	}
+	defer dir.Close()
+
+	if _, err = dir.Readdirnames(1); err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
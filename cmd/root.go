@@ -16,7 +16,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 
@@ -25,7 +24,11 @@ import (
 	"github.com/Chris-Greaves/stencil/confighelper"
 	"github.com/Chris-Greaves/stencil/engine"
 	"github.com/Chris-Greaves/stencil/fetch"
+	"github.com/Chris-Greaves/stencil/inherit"
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/Chris-Greaves/stencil/provenance"
 
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -36,16 +39,36 @@ var (
 	cfgFile                 string
 	templatePath            string
 	usingGit                = false
+	gitURL                  string
+	gitRef                  string
+	gitAuthMethod           transport.AuthMethod
+	subdir                  string
+	shallow                 bool
+	offline                 bool
+	refresh                 bool
+	dryRun                  bool
+	setValues               []string
+	valuesFile              string
+	noInput                 bool
+	noHooks                 bool
+	trustHooks              bool
+	overwrite               bool
+	skipExisting            bool
+	failOnConflict          bool
+	askOnConflict           bool
 	ErrNoArguments          = errors.New("You must provide the path to the template")
 	ErrUnableToFindTemplate = errors.New("stencil was unable to find a local path or git repository using the path provided")
+	ErrConflictingRefs      = errors.New("The ref in the url and the --ref flag don't match")
+	ErrConflictingSubdirs   = errors.New("The subdirectory in the url and the --subdir flag don't match")
+	ErrAskWithoutInput      = errors.New("--ask-on-conflict can't be used with --no-input")
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "stencil [path]",
+	Use:   "stencil [path, url or template name]",
 	Short: "stencil is a tool to parse and execute project templates, using Go's built in template package",
 	Long: `stencil is designed to be a very customisable and user friendly tool, allowing you to execute templates using Go's text/template package.
 
-Note: Only the first argument passed in will be processed.
+Note: Only the first argument passed in will be processed. It can also be the name of a template added with "stencil template add".
 
 By utilising the Go's template package we have opened the ability to create unique and complex templates, easily.
 
@@ -54,51 +77,162 @@ View the documentation on http://christophergreaves.co.uk/projects/stencil/docum
 		if len(args) <= 0 {
 			return ErrNoArguments
 		}
+		if askOnConflict && noInput {
+			return ErrAskWithoutInput
+		}
+
+		source := resolveTemplate(args[0])
+		if !fetch.IsPath(source) {
+			url, ref := fetch.SplitRef(source)
+			url, urlSubdir := fetch.SplitSubdir(url)
+
+			if ref != "" {
+				if gitRef != "" && gitRef != ref {
+					return ErrConflictingRefs
+				}
+				gitRef = ref
+			}
+
+			auth, err := gitAuth(url)
+			if err != nil {
+				return err
+			}
+			gitAuthMethod = auth
 
-		if !fetch.IsPath(args[0]) {
-			if !fetch.IsGitURL(args[0]) {
+			if !templateAvailable(url, gitRef, auth) {
 				return ErrUnableToFindTemplate
-			} else {
-				usingGit = true
+			}
+			usingGit = true
+			gitURL = url
+
+			if urlSubdir != "" {
+				if subdir != "" && subdir != urlSubdir {
+					return ErrConflictingSubdirs
+				}
+				subdir = urlSubdir
 			}
 		}
 
 		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		templatePath := args[0]
-		println(args[0])
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The arguments are valid by now, so errors from here on don't need the usage printed with them
+		cmd.SilenceUsage = true
+
+		templatePath := resolveTemplate(args[0])
 
-		wd, err := os.Getwd()
+		outputPath, err := outputDirectory()
 		if err != nil {
-			log.Panicf("Error getting Working Directory, %v", err)
+			return errors.Wrap(err, "Error getting output directory")
 		}
-		fmt.Printf("Current working directory = %v\n", wd)
+		fmt.Printf("Output directory = %v\n", outputPath)
 
+		record := provenance.Provenance{Subdir: subdir, StencilVersion: version()}
 		if usingGit {
-			dir, err := fetch.PullTemplate(templatePath)
+			cache, err := templateCache()
 			if err != nil {
-				log.Panicf("Error retrieving git repo: %v", err.Error())
+				return errors.Wrap(err, "Error opening template cache")
 			}
-			defer os.RemoveAll(dir)
-			templatePath = dir
+			entry, err := cache.Fetch(gitURL, fetch.PullOptions{Ref: gitRef, Shallow: shallow, Auth: gitAuthMethod}, offline, refresh)
+			if err != nil {
+				return errors.Wrap(err, "Error retrieving git repo")
+			}
+			templatePath = entry.Dir
+			fmt.Printf("Using template %v at commit %v\n", gitURL, entry.Commit)
+			record.Template, record.Ref, record.Commit = gitURL, gitRef, entry.Commit
+		} else if record.Template, err = filepath.Abs(templatePath); err != nil {
+			return errors.Wrap(err, "Error finding template")
+		}
+
+		// Local bases of the template must be inside the directory or repository it came from
+		templateRoot := templatePath
+		templatePath, err = fetch.ResolveSubdir(templatePath, subdir)
+		if err != nil {
+			return errors.Wrap(err, "Error finding template")
 		}
 
-		config, err := confighelper.New(filepath.Join(templatePath, ".stencil/.stencil.json"))
+		created, err := prepareOutputDirectory(cmd, templatePath, outputPath)
 		if err != nil {
-			log.Panicf("Error parsing config file: %v", err.Error())
+			return errors.Wrap(err, "Error preparing output directory")
 		}
+		generated := false
+		defer removeIfCreated(outputPath, created, &generated)
 
-		templateEngine := engine.New()
+		template, bases, err := extendTemplate(templateRoot, templatePath, nil, refresh)
+		if err != nil {
+			return err
+		}
+		defer template.Cleanup()
+		record.Bases = bases
 
-		handler := handlers.NewRootHandler(config, templateEngine, new(IO.CLI))
+		handler, config, err := loadTemplate(template)
+		if err != nil {
+			return err
+		}
+		handler.Conflicts = conflictPolicy()
 
-		handler.OfferConfigOverrides()
+		provided, err := providedValues()
+		if err != nil {
+			return errors.Wrap(err, "Error reading provided values")
+		}
+		if handler, err = handler.ProvideValues(provided); err != nil {
+			return errors.Wrap(err, "Error applying provided values")
+		}
+
+		if noInput {
+			err = handler.ApplyDefaults()
+		} else {
+			err = handler.OfferConfigOverrides()
+		}
+		if err != nil {
+			return errors.Wrap(err, "Error getting template values")
+		}
+
+		if err = handler.ValidateTemplate(template.Dir); err != nil {
+			return errors.Wrap(err, "Error validating template")
+		}
+
+		if dryRun {
+			plan, err := handler.PlanTemplate(template.Dir, outputPath)
+			if err != nil {
+				return errors.Wrap(err, "Error while planning project from template")
+			}
+			handlers.PrintPlan(os.Stdout, outputPath, plan)
+			return nil
+		}
+
+		if err = handler.CheckConflicts(template.Dir, outputPath); err != nil {
+			return errors.Wrap(err, "Error checking for existing files, use --overwrite, --skip-existing or --ask-on-conflict to choose what happens to them")
+		}
+
+		runHooks, err := shouldRunHooks(handler, usingGit || len(bases) > 0)
+		if err != nil {
+			return errors.Wrap(err, "Error confirming hooks")
+		}
+
+		if runHooks {
+			if err = handler.RunHooks(handlers.PreHooks, outputPath); err != nil {
+				return errors.Wrap(err, "Error running hooks")
+			}
+		}
 
-		err = handler.ProcessTemplate(templatePath, wd)
+		err = handler.ProcessTemplate(template.Dir, outputPath)
 		if err != nil {
-			log.Panicf("Error while creating project from template, %v", err.Error())
+			return errors.Wrap(err, "Error while creating project from template")
+		}
+		generated = true
+
+		record.Values, _ = config.Object().(map[string]interface{})
+		if err = record.Write(outputPath); err != nil {
+			return errors.Wrap(err, "Error recording how the project was generated")
+		}
+
+		if runHooks {
+			if err = handler.RunHooks(handlers.PostHooks, outputPath); err != nil {
+				return errors.Wrap(err, "Error running hooks")
+			}
 		}
+		return nil
 	},
 }
 
@@ -115,6 +249,116 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.stencil.yaml)")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the files that would be created without writing anything")
+	rootCmd.Flags().StringArrayVar(&setValues, "set", nil, "set a template value, e.g. --set project.name=foo (can be repeated)")
+	rootCmd.Flags().StringVar(&valuesFile, "values", "", "JSON or YAML file containing template values")
+	rootCmd.Flags().BoolVar(&noInput, "no-input", false, "don't prompt for values, use the defaults and any provided values")
+	rootCmd.Flags().BoolVar(&noHooks, "no-hooks", false, "don't run the template's pre and post generation hooks")
+	rootCmd.Flags().StringVar(&gitRef, "ref", "", "branch, tag or commit of a git template to use, also settable with <url>@<ref>")
+	rootCmd.Flags().StringVar(&subdir, "subdir", "", "subdirectory of the repository or path that contains the template, also settable with <url>//<subdir>")
+	rootCmd.Flags().BoolVar(&shallow, "shallow", false, "only fetch the commit being used from a git template, rather than its whole history")
+	rootCmd.Flags().BoolVar(&offline, "offline", false, "only use git templates from the local cache")
+	rootCmd.Flags().BoolVar(&refresh, "refresh", false, "fetch git templates again even if the cached copy is recent")
+	rootCmd.Flags().BoolVar(&trustHooks, "trust-hooks", false, "run hooks from git templates without asking for confirmation")
+	rootCmd.Flags().BoolVar(&overwrite, "overwrite", false, "replace existing files that differ from the template")
+	rootCmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "keep existing files that differ from the template")
+	rootCmd.Flags().BoolVar(&failOnConflict, "fail-on-conflict", false, "stop without writing anything if an existing file differs from the template (default)")
+	rootCmd.Flags().BoolVar(&askOnConflict, "ask-on-conflict", false, "show a diff for each existing file that differs from the template and ask what to do")
+	rootCmd.MarkFlagsMutuallyExclusive("overwrite", "skip-existing", "fail-on-conflict", "ask-on-conflict")
+}
+
+// loadTemplate reads the configuration and manifest of the template, returning a handler set up to process it
+func loadTemplate(template inherit.Template) (handlers.RootHandler, *confighelper.Conf, error) {
+	templatePath := template.Dir
+	config, err := confighelper.New(filepath.Join(templatePath, ".stencil/.stencil.json"))
+	if err != nil {
+		return handlers.RootHandler{}, nil, errors.Wrap(err, "Error parsing config file")
+	}
+
+	templateManifest, err := manifest.Load(filepath.Join(templatePath, ".stencil"))
+	if err != nil {
+		return handlers.RootHandler{}, nil, errors.Wrap(err, "Error parsing manifest file")
+	}
+
+	templateEngine, err := engine.New().WithDelims(templateManifest.Delimiters.Left, templateManifest.Delimiters.Right).
+		WithBases(template.Bases).WithPartials(filepath.Join(templatePath, ".stencil/partials"))
+	if err != nil {
+		return handlers.RootHandler{}, nil, errors.Wrap(err, "Error loading partials")
+	}
+
+	handler := handlers.NewRootHandler(config, templateEngine, new(IO.CLI))
+	handler.Manifest = templateManifest
+	for _, override := range templateManifest.DelimiterOverrides {
+		handler.ContentEngines = append(handler.ContentEngines, handlers.ContentEngine{
+			Glob:   override.Glob,
+			Engine: templateEngine.WithDelims(override.Left, override.Right),
+		})
+	}
+
+	return handler, config, nil
+}
+
+// conflictPolicy chooses what happens to existing files from the flags, failing by default so local edits are never lost
+func conflictPolicy() handlers.ConflictPolicy {
+	switch {
+	case overwrite:
+		return handlers.ConflictOverwrite
+	case skipExisting:
+		return handlers.ConflictSkip
+	case askOnConflict:
+		return handlers.ConflictAsk
+	default:
+		return handlers.ConflictFail
+	}
+}
+
+// shouldRunHooks decides whether the template's hooks may run. Hooks from git templates, or templates extending one, need confirming,
+// and are skipped when the user can't be asked.
+func shouldRunHooks(handler handlers.RootHandler, fromGit bool) (bool, error) {
+	if noHooks || handler.Manifest.Hooks.Empty() {
+		return false, nil
+	}
+	if !fromGit || trustHooks {
+		return true, nil
+	}
+	if noInput {
+		fmt.Println("Skipping hooks from git template, use --trust-hooks to run them without confirmation")
+		return false, nil
+	}
+	return handler.ConfirmHooks()
+}
+
+// providedValues gathers the template values supplied without prompting. Later sources take precedence:
+// the --values file, then STENCIL_VAR_ environment variables, then --set flags.
+func providedValues() ([]confighelper.Setting, error) {
+	var values []confighelper.Setting
+
+	if valuesFile != "" {
+		fileValues, err := confighelper.LoadValuesFile(valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, fileValues...)
+	}
+
+	values = append(values, confighelper.ValuesFromEnv(os.Environ())...)
+
+	flagValues, err := confighelper.ParseSetValues(setValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(values, flagValues...), nil
+}
+
+// gitAuth finds the credentials for a git template using the "auth" section of the config file
+func gitAuth(url string) (transport.AuthMethod, error) {
+	var authConfig fetch.AuthConfig
+	if err := viper.UnmarshalKey("auth", &authConfig); err != nil {
+		return nil, errors.Wrap(err, "Error reading auth config")
+	}
+
+	return authConfig.AuthFor(url)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -0,0 +1,266 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+// templatesKey is the section of the config file holding the named templates
+const templatesKey = "templates"
+
+var aliasName = regexp.MustCompile("^[a-z0-9][a-z0-9_-]*$")
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage the named templates in your config file",
+	Long: `Named templates are short aliases for template paths and urls, kept in the "templates" section of $HOME/.stencil.yaml:
+
+templates:
+  go-svc: git@github.com:acme/templates.git//go-service@v2
+
+They can then be used in place of the full path, e.g. "stencil go-svc".`,
+}
+
+var templateAddCmd = &cobra.Command{
+	Use:   "add <name> <path or url>",
+	Short: "Add a named template, replacing any existing template with the same name",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := userConfigFile()
+		if err != nil {
+			return err
+		}
+		if err = addTemplate(path, args[0], args[1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Added template '%v' to %v\n", strings.ToLower(args[0]), path)
+		return nil
+	},
+}
+
+var templateRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a named template",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := userConfigFile()
+		if err != nil {
+			return err
+		}
+		return removeTemplate(path, args[0])
+	},
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the named templates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aliases := templateAliases()
+		if len(aliases) == 0 {
+			fmt.Println("No templates have been named, add one with 'stencil template add <name> <path or url>'")
+			return nil
+		}
+
+		names := make([]string, 0, len(aliases))
+		for name := range aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTEMPLATE")
+		for _, name := range names {
+			fmt.Fprintf(w, "%v\t%v\n", name, aliases[name])
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateAddCmd)
+	templateCmd.AddCommand(templateRemoveCmd)
+	templateCmd.AddCommand(templateListCmd)
+}
+
+// addTemplate names the template source in the YAML config file at path, replacing any template with the same name.
+// Names are case insensitive, and can't be the name of a command as the command would always be run instead.
+func addTemplate(path, name, source string) error {
+	lower := strings.ToLower(name)
+	if !aliasName.MatchString(lower) {
+		return fmt.Errorf("Invalid template name '%v', names can only contain letters, numbers, '-' and '_'", name)
+	}
+	if isCommandName(lower) {
+		return fmt.Errorf("Invalid template name '%v', it is the name of a stencil command", name)
+	}
+
+	return editTemplates(path, func(templates *yaml.Node) error {
+		setMappingValue(templates, lower, source)
+		return nil
+	})
+}
+
+// removeTemplate removes the named template from the YAML config file at path
+func removeTemplate(path, name string) error {
+	return editTemplates(path, func(templates *yaml.Node) error {
+		if !removeMappingValue(templates, strings.ToLower(name)) {
+			return fmt.Errorf("There is no template named '%v'", name)
+		}
+		return nil
+	})
+}
+
+// reservedNames are command names that aren't in rootCmd's commands: cobra adds help and completion when it runs, and version is
+// kept free so "stencil version" can't be taken by a template
+var reservedNames = []string{"help", "completion", "version"}
+
+// isCommandName reports whether name is taken by one of stencil's commands
+func isCommandName(name string) bool {
+	for _, reserved := range reservedNames {
+		if name == reserved {
+			return true
+		}
+	}
+	for _, command := range rootCmd.Commands() {
+		if command.Name() == name || command.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateAliases returns the named templates from the config file. Names are case insensitive, so are always lower case.
+func templateAliases() map[string]string {
+	return viper.GetStringMapString(templatesKey)
+}
+
+// resolveTemplate swaps a template name for the path or url it stands for, returning anything else unchanged
+func resolveTemplate(input string) string {
+	if source, ok := templateAliases()[strings.ToLower(input)]; ok {
+		return source
+	}
+	return input
+}
+
+// userConfigFile returns the config file to store named templates in, which is created in the home directory if there isn't one yet
+func userConfigFile() (string, error) {
+	path := cfgFile
+	if path == "" {
+		path = viper.ConfigFileUsed()
+	}
+	if path == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, ".stencil.yaml")
+	}
+
+	if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+		return "", fmt.Errorf("Named templates can only be changed in a YAML config file, not '%v'", path)
+	}
+	return path, nil
+}
+
+// editTemplates applies the edit to the templates section of the YAML config file, keeping the rest of the file and its comments as they were
+func editTemplates(path string, edit func(templates *yaml.Node) error) error {
+	var doc yaml.Node
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "Error reading config file '%v'", path)
+	}
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return errors.Wrapf(err, "Error parsing config file '%v'", path)
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("Config file '%v' must contain a mapping", path)
+	}
+
+	templates := mappingValue(root, templatesKey)
+	if templates == nil || templates.Kind != yaml.MappingNode {
+		templates = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingNode(root, templatesKey, templates)
+	}
+	if err = edit(templates); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(path, out.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "Error writing config file '%v'", path)
+	}
+	return nil
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.ToLower(mapping.Content[i].Value) == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key, value string) {
+	setMappingNode(mapping, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+func setMappingNode(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.ToLower(mapping.Content[i].Value) == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func removeMappingValue(mapping *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.ToLower(mapping.Content[i].Value) == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddTemplateKeepsTheRestOfTheConfig(t *testing.T) {
+	path, dir := createConfigFile(t, `# stencil settings
+auth:
+  tokenEnv: GITLAB_TOKEN # checked first
+templates:
+  # the service template
+  go-svc: git@github.com:acme/templates.git//go-service@v2
+`)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, addTemplate(path, "React-App", "https://github.com/acme/react-template"))
+	require.NoError(t, addTemplate(path, "GO-SVC", "git@github.com:acme/templates.git//go-service@v3"))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# stencil settings
+auth:
+  tokenEnv: GITLAB_TOKEN # checked first
+templates:
+  # the service template
+  go-svc: git@github.com:acme/templates.git//go-service@v3
+  react-app: https://github.com/acme/react-template
+`, string(data))
+}
+
+func TestAddTemplateCreatesTheConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stencil-config-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".stencil.yaml")
+
+	require.NoError(t, addTemplate(path, "go-svc", "./templates/go"))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "templates:\n  go-svc: ./templates/go\n", string(data))
+}
+
+func TestAddTemplateRejectsInvalidAndCommandNames(t *testing.T) {
+	path, dir := createConfigFile(t, "")
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"cache", "Template", "update", "version", "help", "completion"} {
+		err := addTemplate(path, name, "./template")
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "it is the name of a stencil command", name)
+	}
+
+	err := addTemplate(path, "my template", "./template")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid template name 'my template'")
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, string(data))
+}
+
+func TestRemoveTemplateKeepsTheRestOfTheConfig(t *testing.T) {
+	path, dir := createConfigFile(t, `cache-max-age: 2h # longer than the default
+templates:
+  go-svc: ./go
+  react-app: ./react
+`)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, removeTemplate(path, "Go-Svc"))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "cache-max-age: 2h # longer than the default\ntemplates:\n  react-app: ./react\n", string(data))
+
+	err = removeTemplate(path, "go-svc")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "There is no template named 'go-svc'")
+}
+
+func TestResolveTemplateUsesNamedTemplates(t *testing.T) {
+	path, dir := createConfigFile(t, "templates:\n  go-svc: git@github.com:acme/templates.git//go-service@v2\n")
+	defer os.RemoveAll(dir)
+	defer viper.Reset()
+
+	viper.SetConfigFile(path)
+	require.NoError(t, viper.ReadInConfig())
+
+	assert.Equal(t, "git@github.com:acme/templates.git//go-service@v2", resolveTemplate("Go-Svc"))
+	assert.Equal(t, "./go-svc", resolveTemplate("./go-svc"))
+	assert.Equal(t, "https://github.com/acme/other", resolveTemplate("https://github.com/acme/other"))
+}
+
+func createConfigFile(t *testing.T, contents string) (string, string) {
+	dir, err := ioutil.TempDir("", "stencil-config-")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, ".stencil.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path, dir
+}
@@ -0,0 +1,186 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Chris-Greaves/stencil/cmd/handlers"
+	"github.com/Chris-Greaves/stencil/fetch"
+	"github.com/Chris-Greaves/stencil/inherit"
+	"github.com/Chris-Greaves/stencil/provenance"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	updateRef         string
+	ErrUpdateNeedsGit = errors.New("Only projects generated from a git template can be updated, as the commit they were generated from is needed to tell local changes apart from the template's")
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [project directory]",
+	Short: "Update a generated project to a newer version of its template",
+	Long: `Update renders the template the project was generated from again, at the latest commit of the same ref (or the ref given with --ref),
+using the answers recorded in ` + provenance.FileName + `.
+
+Files only changed by the template are replaced, files only changed locally are left alone, and files changed on both sides are merged.
+Where the changes overlap, conflict markers are left in the file to resolve by hand. Binary files that can't be merged keep the local
+version, with the template's version written alongside it as <file>` + handlers.NewFileSuffix + `.
+
+Values added to the template since the project was generated use their defaults. Hooks are not run.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectPath := "."
+		if len(args) > 0 {
+			projectPath = args[0]
+		}
+		projectPath, err := filepath.Abs(projectPath)
+		if err != nil {
+			return err
+		}
+
+		record, err := provenance.Load(projectPath)
+		if err != nil {
+			return err
+		}
+		if record.Commit == "" {
+			return ErrUpdateNeedsGit
+		}
+
+		ref := updateRef
+		if ref == "" {
+			ref = record.Ref
+		}
+
+		auth, err := gitAuth(record.Template)
+		if err != nil {
+			return err
+		}
+		cache, err := templateCache()
+		if err != nil {
+			return err
+		}
+
+		latest, err := cache.Fetch(record.Template, fetch.PullOptions{Ref: ref, Auth: auth}, false, true)
+		if err != nil {
+			return errors.Wrap(err, "Error retrieving git repo")
+		}
+		latestTemplate, latestBases, err := extendForUpdate(latest.Dir, record.Subdir, nil, true)
+		if err != nil {
+			return errors.Wrapf(err, "Error extending commit %.12v", latest.Commit)
+		}
+		defer latestTemplate.Cleanup()
+		if latest.Commit == record.Commit && sameCommits(latestBases, record.Bases) {
+			fmt.Printf("Already up to date with %v at commit %.12v\n", record.Template, record.Commit)
+			return nil
+		}
+		fmt.Printf("Updating %v from commit %.12v to %.12v\n", record.Template, record.Commit, latest.Commit)
+
+		previous, err := cache.Fetch(record.Template, fetch.PullOptions{Ref: record.Commit, Auth: auth}, false, false)
+		if err != nil {
+			return errors.Wrap(err, "Error retrieving the version of the template the project was generated from")
+		}
+		// The templates extended are pinned to the commits the project was generated from, so their changes are updated too
+		previousTemplate, _, err := extendForUpdate(previous.Dir, record.Subdir, record.Bases, false)
+		if err != nil {
+			return errors.Wrapf(err, "Error extending commit %.12v", record.Commit)
+		}
+		defer previousTemplate.Cleanup()
+
+		base, _, err := renderForUpdate(previousTemplate, record, projectPath)
+		if err != nil {
+			return errors.Wrapf(err, "Error rendering commit %.12v", record.Commit)
+		}
+		updated, config, err := renderForUpdate(latestTemplate, record, projectPath)
+		if err != nil {
+			return errors.Wrapf(err, "Error rendering commit %.12v", latest.Commit)
+		}
+
+		results, err := handlers.UpdateProject(projectPath, base, updated)
+		if err != nil {
+			return err
+		}
+
+		conflicts := 0
+		for _, result := range results {
+			fmt.Printf("%-9v %v\n", result.Status, filepath.ToSlash(result.RelPath))
+			if result.Status == handlers.UpdateConflict {
+				conflicts++
+			}
+		}
+
+		record.Ref, record.Commit, record.Bases, record.StencilVersion = ref, latest.Commit, latestBases, version()
+		record.Values, _ = config.Object().(map[string]interface{})
+		if err = record.Write(projectPath); err != nil {
+			return err
+		}
+
+		if conflicts > 0 {
+			fmt.Fprintf(os.Stderr, "%v files have conflicts, resolve the conflict markers (or %v files) before using the project\n", conflicts, handlers.NewFileSuffix)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().StringVar(&updateRef, "ref", "", "branch, tag or commit of the template to update to (default is the ref the project was generated from)")
+}
+
+// extendForUpdate layers the templates extended by the template in subdir of the clone in dir underneath it, see extendTemplate
+func extendForUpdate(dir, subdir string, pinned map[string]string, refreshBases bool) (inherit.Template, map[string]string, error) {
+	templatePath, err := fetch.ResolveSubdir(dir, subdir)
+	if err != nil {
+		return inherit.Template{}, nil, err
+	}
+	return extendTemplate(dir, templatePath, pinned, refreshBases)
+}
+
+// renderForUpdate renders the template in memory, using the answers recorded for the project
+func renderForUpdate(template inherit.Template, record provenance.Provenance, projectPath string) (map[string]handlers.RenderedFile, handlers.Config, error) {
+	handler, config, err := loadTemplate(template)
+	if err != nil {
+		return nil, nil, err
+	}
+	if handler, err = handler.ProvideValues(record.Settings()); err != nil {
+		return nil, nil, err
+	}
+	if err = handler.ApplyDefaults(); err != nil {
+		return nil, nil, err
+	}
+
+	files, err := handler.RenderFiles(template.Dir, projectPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, config, nil
+}
+
+// sameCommits reports whether the same commit of every extended template was used
+func sameCommits(commits, recorded map[string]string) bool {
+	if len(commits) != len(recorded) {
+		return false
+	}
+	for source, commit := range commits {
+		if recorded[source] != commit {
+			return false
+		}
+	}
+	return true
+}
@@ -25,9 +25,10 @@ import (
 )
 
 // Setting is a simple struct to represent a setting in a Conf. This is used when trying to Get or Set values in the Conf.
+// Value holds the JSON type of the setting: a string, bool, float64, []interface{} or nil.
 type Setting struct {
 	Name  string
-	Value string
+	Value interface{}
 }
 
 // Conf encompasses the anonymous json object for a template config.
@@ -72,9 +73,19 @@ func (c *Conf) GetAllValues() ([]Setting, error) {
 }
 
 // SetValues will take an array of settings to put each one into the Conf. If the setting already exists it will update the value, else it will add the new setting.
+// String values given for an existing non-string setting are converted to that setting's type.
 func (c *Conf) SetValues(settings []Setting) error {
 	for _, setting := range settings {
-		_, err := c.raw.SetP(setting.Value, setting.Name)
+		value := setting.Value
+		if input, ok := value.(string); ok && c.raw.ExistsP(setting.Name) {
+			converted, err := ParseValue(input, c.raw.Path(setting.Name).Data())
+			if err != nil {
+				return fmt.Errorf("Invalid value for '%v'. Error: %v", setting.Name, err.Error())
+			}
+			value = converted
+		}
+
+		_, err := c.raw.SetP(value, setting.Name)
 		if err != nil {
 			return err
 		}
@@ -102,7 +113,7 @@ func getValuesOrCallChildren(children map[string]*gabs.Container, sets *[]Settin
 	for child := range children {
 		nextChildren, _ := children[child].ChildrenMap()
 		if len(nextChildren) < 1 {
-			*sets = append(*sets, Setting{Name: objPath + child, Value: children[child].Data().(string)})
+			*sets = append(*sets, Setting{Name: objPath + child, Value: children[child].Data()})
 		} else {
 			getValuesOrCallChildren(nextChildren, sets, objPath+child+".")
 		}
@@ -100,7 +100,7 @@ func TestYouCanGetAllValuesFromConf(t *testing.T) {
 	require.Greater(t, len(sets), 1, "GetAllValuesFromFile should have returned an array of settings")
 
 	// mapping to make asserts more readable
-	settingMap := map[string]string{}
+	settingMap := map[string]interface{}{}
 	for i := range sets {
 		settingMap[sets[i].Name] = sets[i].Value
 	}
@@ -143,13 +143,85 @@ func TestGetAllValuesReturnsErrorWhenEmptyJson(t *testing.T) {
 	require.Error(t, err)
 }
 
+const typedFileContents = `{
+	"name": "service",
+	"port": 8080,
+	"features": {
+		"docker": true
+	},
+	"tags": ["api", "internal"],
+	"owner": null
+}`
+
+func TestGetAllValuesReturnsTypedValues(t *testing.T) {
+	conf := createConfFromContents(t, typedFileContents)
+
+	sets, err := conf.GetAllValues()
+	require.NoError(t, err)
+
+	settingMap := map[string]interface{}{}
+	for i := range sets {
+		settingMap[sets[i].Name] = sets[i].Value
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"name":            "service",
+		"port":            float64(8080),
+		"features.docker": true,
+		"tags":            []interface{}{"api", "internal"},
+		"owner":           nil,
+	}, settingMap)
+}
+
+func TestSetValuesConvertsStringsToExistingType(t *testing.T) {
+	conf := createConfFromContents(t, typedFileContents)
+
+	err := conf.SetValues([]Setting{
+		{Name: "features.docker", Value: "false"},
+		{Name: "port", Value: "9090"},
+		{Name: "tags", Value: "public"},
+		{Name: "owner", Value: "team-a"},
+		{Name: "new.value", Value: "true"},
+	})
+	require.NoError(t, err)
+
+	obj := conf.Object().(map[string]interface{})
+	assert.Equal(t, false, obj["features"].(map[string]interface{})["docker"])
+	assert.Equal(t, float64(9090), obj["port"])
+	assert.Equal(t, []interface{}{"public"}, obj["tags"])
+	assert.Equal(t, "team-a", obj["owner"])
+	assert.Equal(t, "true", obj["new"].(map[string]interface{})["value"])
+}
+
+func TestSetValuesKeepsTypedValues(t *testing.T) {
+	conf := createConfFromContents(t, typedFileContents)
+
+	err := conf.SetValues([]Setting{{Name: "features.docker", Value: false}})
+	require.NoError(t, err)
+
+	obj := conf.Object().(map[string]interface{})
+	assert.Equal(t, false, obj["features"].(map[string]interface{})["docker"])
+}
+
+func TestSetValuesErrorsWhenStringCantBeConverted(t *testing.T) {
+	conf := createConfFromContents(t, typedFileContents)
+
+	err := conf.SetValues([]Setting{{Name: "features.docker", Value: "maybe"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "features.docker")
+}
+
 func createNewConf(t *testing.T) *Conf {
+	return createConfFromContents(t, exampleFileContents)
+}
+
+func createConfFromContents(t *testing.T, contents string) *Conf {
 	file, err := ioutil.TempFile("", "fakefile-*.json")
 	require.NoError(t, err, "Unable to create temp file for test")
 
 	defer os.RemoveAll(file.Name())
 
-	file.WriteString(exampleFileContents)
+	file.WriteString(contents)
 	file.Close()
 
 	conf, err := New(file.Name())
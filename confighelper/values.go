@@ -0,0 +1,197 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confighelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "go.yaml.in/yaml/v3"
+)
+
+// EnvPrefix is the prefix of environment variables that provide values for a template, e.g. STENCIL_VAR_project__name sets "project.name"
+const EnvPrefix = "STENCIL_VAR_"
+
+// ParseSetValues converts a list of "name=value" pairs, as passed to --set, into Settings
+func ParseSetValues(pairs []string) ([]Setting, error) {
+	var sets []Setting
+	for _, pair := range pairs {
+		idx := strings.Index(pair, "=")
+		if idx < 1 {
+			return nil, fmt.Errorf("Invalid value '%v', expected the format name=value", pair)
+		}
+		sets = append(sets, Setting{Name: pair[:idx], Value: pair[idx+1:]})
+	}
+	return sets, nil
+}
+
+// LoadValuesFile reads a JSON or YAML answers file and flattens it into Settings using dotted names
+func LoadValuesFile(path string) ([]Setting, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error ocurred reading values file. Error: %v", err.Error())
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+		if err == nil {
+			// YAML decodes numbers as int and can hold types JSON can't, so convert the values to the JSON types Settings use
+			if data, err = json.Marshal(values); err == nil {
+				values = map[string]interface{}{}
+				err = json.Unmarshal(data, &values)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Values file '%v' must have a '.json', '.yaml' or '.yml' extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error ocurred parsing values file. Error: %v", err.Error())
+	}
+
+	return FlattenValues(values), nil
+}
+
+// ValuesFromEnv finds every variable in environ (formatted as "KEY=value") that starts with EnvPrefix and converts it into a Setting.
+// A double underscore in the key separates the levels of the setting name.
+func ValuesFromEnv(environ []string) []Setting {
+	var sets []Setting
+	for _, entry := range environ {
+		if !strings.HasPrefix(entry, EnvPrefix) {
+			continue
+		}
+		idx := strings.Index(entry, "=")
+		if idx < 0 {
+			continue
+		}
+		name := strings.ReplaceAll(entry[len(EnvPrefix):idx], "__", ".")
+		if name == "" {
+			continue
+		}
+		sets = append(sets, Setting{Name: name, Value: entry[idx+1:]})
+	}
+
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+	return sets
+}
+
+// ParseValue converts user input into a value of the same JSON type as like. Lists accept either a JSON array or comma separated items,
+// and settings without a value (null) accept any JSON value, falling back to a plain string.
+func ParseValue(input string, like interface{}) (interface{}, error) {
+	switch typed := like.(type) {
+	case string:
+		return input, nil
+	case bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(input))
+		if err != nil {
+			return nil, fmt.Errorf("'%v' is not a boolean, expected true or false", input)
+		}
+		return value, nil
+	case float64, int:
+		value, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%v' is not a number", input)
+		}
+		return value, nil
+	case []interface{}:
+		return parseList(input, typed)
+	case nil:
+		var value interface{}
+		if err := json.Unmarshal([]byte(input), &value); err == nil {
+			return value, nil
+		}
+		return input, nil
+	default:
+		return nil, fmt.Errorf("Settings of type %T can't be set from text", like)
+	}
+}
+
+// FormatValue converts a setting value into the text a user would type to enter it
+func FormatValue(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(typed))
+		for i, item := range typed {
+			items[i] = FormatValue(item)
+		}
+		return strings.Join(items, ", ")
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func parseList(input string, like []interface{}) (interface{}, error) {
+	trimmed := strings.TrimSpace(input)
+	if strings.HasPrefix(trimmed, "[") {
+		var list []interface{}
+		if err := json.Unmarshal([]byte(trimmed), &list); err != nil {
+			return nil, fmt.Errorf("'%v' is not a valid JSON array", input)
+		}
+		return list, nil
+	}
+
+	list := []interface{}{}
+	if trimmed == "" {
+		return list, nil
+	}
+
+	var itemLike interface{} = ""
+	if len(like) > 0 {
+		itemLike = like[0]
+	}
+	for _, item := range strings.Split(trimmed, ",") {
+		value, err := ParseValue(strings.TrimSpace(item), itemLike)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// FlattenValues converts nested values, such as those returned by Conf.Object, into Settings using dotted names
+func FlattenValues(values map[string]interface{}) []Setting {
+	var sets []Setting
+	flattenValues(values, "", &sets)
+	return sets
+}
+
+func flattenValues(values map[string]interface{}, objPath string, sets *[]Setting) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if child, ok := values[key].(map[string]interface{}); ok && len(child) > 0 {
+			flattenValues(child, objPath+key+".", sets)
+			continue
+		}
+		*sets = append(*sets, Setting{Name: objPath + key, Value: values[key]})
+	}
+}
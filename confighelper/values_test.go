@@ -0,0 +1,166 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confighelper
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSetValuesSplitsOnFirstEquals(t *testing.T) {
+	sets, err := ParseSetValues([]string{"project.name=foo", "database.connection_string=a=b"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Setting{
+		{Name: "project.name", Value: "foo"},
+		{Name: "database.connection_string", Value: "a=b"},
+	}, sets)
+}
+
+func TestParseSetValuesErrorsWithoutName(t *testing.T) {
+	_, err := ParseSetValues([]string{"=foo"})
+	assert.Error(t, err)
+
+	_, err = ParseSetValues([]string{"project.name"})
+	assert.Error(t, err)
+}
+
+func TestLoadValuesFileReadsJSON(t *testing.T) {
+	path := createValuesFile(t, "answers-*.json", `{"project": {"name": "foo", "user": {"name": "bar"}}, "port": 8080}`)
+	defer os.RemoveAll(path)
+
+	sets, err := LoadValuesFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Setting{
+		{Name: "port", Value: float64(8080)},
+		{Name: "project.name", Value: "foo"},
+		{Name: "project.user.name", Value: "bar"},
+	}, sets)
+}
+
+func TestLoadValuesFileReadsYAML(t *testing.T) {
+	path := createValuesFile(t, "answers-*.yaml", "project:\n  name: foo\nport: 9090\nratio: 0.5\ndocker: true\ntags:\n  - api\n  - 2\nowner: ~\n")
+	defer os.RemoveAll(path)
+
+	sets, err := LoadValuesFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Setting{
+		{Name: "docker", Value: true},
+		{Name: "owner", Value: nil},
+		{Name: "port", Value: float64(9090)},
+		{Name: "project.name", Value: "foo"},
+		{Name: "ratio", Value: 0.5},
+		{Name: "tags", Value: []interface{}{"api", float64(2)}},
+	}, sets)
+}
+
+func TestFlattenValuesKeepsEmptyObjects(t *testing.T) {
+	sets := FlattenValues(map[string]interface{}{
+		"project": map[string]interface{}{"name": "foo", "tags": []interface{}{"a"}},
+		"extra":   map[string]interface{}{},
+	})
+
+	assert.Equal(t, []Setting{
+		{Name: "extra", Value: map[string]interface{}{}},
+		{Name: "project.name", Value: "foo"},
+		{Name: "project.tags", Value: []interface{}{"a"}},
+	}, sets)
+}
+
+func TestLoadValuesFileErrorsOnUnknownExtension(t *testing.T) {
+	path := createValuesFile(t, "answers-*.txt", "project.name=foo")
+	defer os.RemoveAll(path)
+
+	_, err := LoadValuesFile(path)
+	assert.Error(t, err)
+}
+
+func TestValuesFromEnvOnlyUsesPrefixedVariables(t *testing.T) {
+	sets := ValuesFromEnv([]string{
+		"HOME=/root",
+		"STENCIL_VAR_project__name=foo",
+		"STENCIL_VAR_port=8080",
+		"STENCIL_VAR_=ignored",
+	})
+
+	assert.Equal(t, []Setting{
+		{Name: "port", Value: "8080"},
+		{Name: "project.name", Value: "foo"},
+	}, sets)
+}
+
+func TestParseValueConvertsToTypeOfExistingValue(t *testing.T) {
+	cases := []struct {
+		input    string
+		like     interface{}
+		expected interface{}
+	}{
+		{"hello world", "default", "hello world"},
+		{"true", false, true},
+		{" FALSE ", true, false},
+		{"8080", float64(80), float64(8080)},
+		{"1.5", float64(0), 1.5},
+		{"a, b,c", []interface{}{"x"}, []interface{}{"a", "b", "c"}},
+		{"1, 2", []interface{}{float64(0)}, []interface{}{float64(1), float64(2)}},
+		{`["a", 1]`, []interface{}{}, []interface{}{"a", float64(1)}},
+		{"", []interface{}{"x"}, []interface{}{}},
+		{"true", nil, true},
+		{"plain text", nil, "plain text"},
+	}
+
+	for _, c := range cases {
+		value, err := ParseValue(c.input, c.like)
+		require.NoError(t, err, c.input)
+		assert.Equal(t, c.expected, value, c.input)
+	}
+}
+
+func TestParseValueErrorsOnInvalidInput(t *testing.T) {
+	_, err := ParseValue("yes please", false)
+	assert.Error(t, err)
+
+	_, err = ParseValue("eighty", float64(80))
+	assert.Error(t, err)
+
+	_, err = ParseValue("1, two", []interface{}{float64(0)})
+	assert.Error(t, err)
+
+	_, err = ParseValue("[1, 2", []interface{}{})
+	assert.Error(t, err)
+}
+
+func TestFormatValue(t *testing.T) {
+	assert.Equal(t, "", FormatValue(nil))
+	assert.Equal(t, "true", FormatValue(true))
+	assert.Equal(t, "8080", FormatValue(float64(8080)))
+	assert.Equal(t, "a, 1", FormatValue([]interface{}{"a", float64(1)}))
+	assert.Equal(t, "text", FormatValue("text"))
+}
+
+func createValuesFile(t *testing.T, pattern, contents string) string {
+	file, err := ioutil.TempFile("", pattern)
+	require.NoError(t, err, "Unable to create temp file for test")
+
+	file.WriteString(contents)
+	file.Close()
+
+	return file.Name()
+}
@@ -17,26 +17,50 @@ package engine
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/pkg/errors"
 )
 
 // DefaultEngine is a default implementation of the Template Engine needed for Stencil
 type DefaultEngine struct {
+	funcs      template.FuncMap
+	leftDelim  string
+	rightDelim string
+	// partials are the templates shared by every file and path, see WithPartials
+	partials     map[string]*parse.Tree
+	partialFiles map[string]string
+	// bases are the files replaced by each file of an extending template, see WithBases
+	bases map[string]string
 }
 
 // New Creates a new instance of the Default Engine
 func New() DefaultEngine {
-	return DefaultEngine{}
+	return DefaultEngine{funcs: FuncMap()}
+}
+
+// WithDelims returns a copy of the engine that uses the given action delimiters instead of "{{" and "}}".
+// An empty delimiter keeps the default.
+func (e DefaultEngine) WithDelims(left, right string) DefaultEngine {
+	e.leftDelim = left
+	e.rightDelim = right
+	return e
+}
+
+// WithBases returns a copy of the engine that parses each file in bases on top of the base file it maps to, which may itself have a base.
+// The file's {{ define }}s replace the base's {{ block }}s, and a file containing only {{ define }}s keeps the base's contents.
+func (e DefaultEngine) WithBases(bases map[string]string) DefaultEngine {
+	e.bases = bases
+	return e
 }
 
 // ParseAndExecutePath will parse the path as a template and execute it using the settings provided
 func (e DefaultEngine) ParseAndExecutePath(path string, settings interface{}) (string, error) {
-	mainTemplate := template.New("main")
-
-	tmpl, err := mainTemplate.Parse(path)
+	tmpl, err := e.parse("main", path)
 	if err != nil {
 		return "", errors.Wrapf(err, "Error parsing path '%v' to template", path)
 	}
@@ -51,7 +75,15 @@ func (e DefaultEngine) ParseAndExecutePath(path string, settings interface{}) (s
 
 // ParseAndExecuteFile will parse a file as a template and execute it using the settings provided. it will write out to the destinationPath using the FileMode supplied.
 func (e DefaultEngine) ParseAndExecuteFile(sourcePath string, settings interface{}, wr io.Writer) error {
-	fileTemplate, err := template.ParseFiles(sourcePath)
+	var layers []string
+	for layer, found := sourcePath, true; found; layer, found = e.bases[layer] {
+		contents, err := readTemplateFile(layer)
+		if err != nil {
+			return errors.Wrapf(err, "Error Parsing template for file '%v'", sourcePath)
+		}
+		layers = append([]string{string(contents)}, layers...)
+	}
+	fileTemplate, err := e.parse(filepath.Base(sourcePath), layers...)
 	if err != nil {
 		return errors.Wrapf(err, "Error Parsing template for file '%v'", sourcePath)
 	}
@@ -62,3 +94,15 @@ func (e DefaultEngine) ParseAndExecuteFile(sourcePath string, settings interface
 
 	return nil
 }
+
+// readTemplateFile reads a template file, refusing to follow a symlink to somewhere that may be outside the template
+func readTemplateFile(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil, errors.Errorf("'%v' is a symlink, which can't be used as a template", path)
+	}
+	return ioutil.ReadFile(path)
+}
@@ -90,6 +90,52 @@ func TestMissingPropertyInSettingsReturnsErrorWhenExecutinFile(t *testing.T) {
 	assert.Contains(t, err.Error(), "Error executing template file", "Incorrect error returned")
 }
 
+func TestPathCanBeExecutedWithCustomDelims(t *testing.T) {
+	executedPath, err := defaultEngine.WithDelims("[[", "]]").ParseAndExecutePath("[[.ProjectName]]-{{.Text}}", validSettings)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Foobar-{{.Text}}", executedPath)
+}
+
+func TestFileCanBeExecutedWithCustomDelims(t *testing.T) {
+	testFilePath := CreateTestTemplateFile(t, "run: ${{ github.sha }} [[ .Text | upper ]]")
+	defer os.RemoveAll(testFilePath)
+	var b bytes.Buffer
+
+	err := defaultEngine.WithDelims("[[", "]]").ParseAndExecuteFile(testFilePath, validSettings, &b)
+	require.NoError(t, err)
+
+	assert.Equal(t, "run: ${{ github.sha }} HELLO WORLD", b.String())
+}
+
+func TestFileCanOverrideTheBlocksOfItsBases(t *testing.T) {
+	grandBase := CreateTestTemplateFile(t, `name: {{ .ProjectName }}{{ block "steps" . }} none{{ end }}{{ block "extra" . }}{{ end }}`)
+	defer os.RemoveAll(grandBase)
+	base := CreateTestTemplateFile(t, `{{ define "steps" }} lint{{ end }}`)
+	defer os.RemoveAll(base)
+	child := CreateTestTemplateFile(t, "{{ define \"extra\" }} {{ .Text | upper }}{{ end }}\n")
+	defer os.RemoveAll(child)
+	var b bytes.Buffer
+
+	err := defaultEngine.WithBases(map[string]string{child: base, base: grandBase}).ParseAndExecuteFile(child, validSettings, &b)
+	require.NoError(t, err)
+
+	assert.Equal(t, "name: Foobar lint HELLO WORLD", b.String())
+}
+
+func TestFileWithContentsReplacesItsBase(t *testing.T) {
+	base := CreateTestTemplateFile(t, `{{ block "steps" . }}base{{ end }}`)
+	defer os.RemoveAll(base)
+	child := CreateTestTemplateFile(t, `own {{ template "steps" . }}`)
+	defer os.RemoveAll(child)
+	var b bytes.Buffer
+
+	err := defaultEngine.WithBases(map[string]string{child: base}).ParseAndExecuteFile(child, validSettings, &b)
+	require.NoError(t, err)
+
+	assert.Equal(t, "own base", b.String())
+}
+
 func CreateTestTemplateFile(t *testing.T, contents string) string {
 	file, err := ioutil.TempFile("", "stencil-test-file-*.txt")
 	require.NoError(t, err, "Unable to create temp file for test")
@@ -98,3 +144,19 @@ func CreateTestTemplateFile(t *testing.T, contents string) string {
 
 	return file.Name()
 }
+
+func TestFileRefusesToReadSymlinks(t *testing.T) {
+	testFilePath := CreateTestTemplateFile(t, "secret")
+	defer os.RemoveAll(testFilePath)
+	link := testFilePath + ".link"
+	if err := os.Symlink(testFilePath, link); err != nil {
+		t.Skip("symlinks aren't supported")
+	}
+	defer os.RemoveAll(link)
+	var b bytes.Buffer
+
+	err := defaultEngine.ParseAndExecuteFile(link, validSettings, &b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is a symlink")
+	assert.Empty(t, b.String())
+}
@@ -0,0 +1,58 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// templateErrorPattern matches the errors text/template returns from parsing ("template: name:line: message") and
+// executing ("template: name:line:column: executing "name" at <action>: message")
+var templateErrorPattern = regexp.MustCompile(`(?s)^template: ([^:]*):(\d+)(?::(\d+))?: (.*)$`)
+
+// TemplateError is an error from text/template split into the template it happened in, where in it and what went wrong.
+// Column is 0 when text/template doesn't report one, which is the case for parse errors.
+type TemplateError struct {
+	Name    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// ParseTemplateError finds the text/template error behind err, returning false if it isn't one
+func ParseTemplateError(err error) (TemplateError, bool) {
+	if err == nil {
+		return TemplateError{}, false
+	}
+
+	match := templateErrorPattern.FindStringSubmatch(errors.Cause(err).Error())
+	if match == nil {
+		return TemplateError{}, false
+	}
+
+	templateErr := TemplateError{Name: match[1], Message: match[4]}
+	templateErr.Line, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		templateErr.Column, _ = strconv.Atoi(match[3])
+	}
+	// The name is already known, so only keep the action that failed
+	templateErr.Message = strings.TrimPrefix(templateErr.Message, `executing "`+templateErr.Name+`" `)
+
+	return templateErr, true
+}
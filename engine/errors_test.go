@@ -0,0 +1,59 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplateErrorFromParsingFile(t *testing.T) {
+	testFilePath := CreateTestTemplateFile(t, "first line\n{{ if }}")
+	defer os.RemoveAll(testFilePath)
+	var b bytes.Buffer
+
+	err := defaultEngine.ParseAndExecuteFile(testFilePath, validSettings, &b)
+	require.Error(t, err)
+
+	templateErr, ok := ParseTemplateError(err)
+	require.True(t, ok)
+	assert.Equal(t, TemplateError{Name: filepath.Base(testFilePath), Line: 2, Message: "missing value for if"}, templateErr)
+}
+
+func TestParseTemplateErrorFromExecutingPath(t *testing.T) {
+	_, err := defaultEngine.ParseAndExecutePath("{{.ProjectName}}-{{.NonExistantValue}}", validSettings)
+	require.Error(t, err)
+
+	templateErr, ok := ParseTemplateError(err)
+	require.True(t, ok)
+	assert.Equal(t, "main", templateErr.Name)
+	assert.Equal(t, 1, templateErr.Line)
+	assert.Equal(t, 19, templateErr.Column)
+	assert.Contains(t, templateErr.Message, "at <.NonExistantValue>: can't evaluate field NonExistantValue")
+}
+
+func TestParseTemplateErrorIgnoresOtherErrors(t *testing.T) {
+	_, ok := ParseTemplateError(errors.Wrap(errors.New("file not found"), "Error reading file"))
+	assert.False(t, ok)
+
+	_, ok = ParseTemplateError(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,342 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+// FuncMap returns the library of functions available to every template executed by the DefaultEngine.
+// Argument order follows the Sprig conventions so values can be piped in as the last argument.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		// Strings
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"indent":     indent,
+		"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+
+		// Case conversion
+		"snakecase":  snakeCase,
+		"kebabcase":  kebabCase,
+		"camelcase":  camelCase,
+		"pascalcase": pascalCase,
+		"pluralize":  pluralize,
+
+		// Regular expressions
+		"regexMatch":      regexMatch,
+		"regexFind":       regexFind,
+		"regexFindAll":    regexFindAll,
+		"regexReplaceAll": regexReplaceAll,
+
+		// Defaults
+		"default":  defaultValue,
+		"coalesce": coalesce,
+		"empty":    empty,
+		"ternary":  ternary,
+
+		// Dates
+		"now":  time.Now,
+		"date": date,
+
+		// Encoding and hashing
+		"uuid":      newUUID,
+		"sha256sum": sha256sum,
+		"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":    b64dec,
+		"toJson":    toJSON,
+		"toYaml":    toYAML,
+	}
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+func join(sep string, list interface{}) string {
+	val := reflect.ValueOf(list)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return fmt.Sprint(list)
+	}
+
+	parts := make([]string, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		parts[i] = fmt.Sprint(val.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// splitWords breaks an identifier into lower case words, splitting on any non alphanumeric character
+// and on the boundaries of camel case words, so "myService", "my-service" and "MY_SERVICE" all produce [my service].
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
+
+func snakeCase(s string) string {
+	return strings.Join(splitWords(s), "_")
+}
+
+func kebabCase(s string) string {
+	return strings.Join(splitWords(s), "-")
+}
+
+func pascalCase(s string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, "")
+}
+
+func camelCase(s string) string {
+	pascal := []rune(pascalCase(s))
+	if len(pascal) == 0 {
+		return ""
+	}
+	pascal[0] = unicode.ToLower(pascal[0])
+	return string(pascal)
+}
+
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"person": "people",
+	"man":    "men",
+	"woman":  "women",
+	"mouse":  "mice",
+	"goose":  "geese",
+	"tooth":  "teeth",
+	"foot":   "feet",
+}
+
+// pluralize returns the English plural of a singular noun using a small set of common rules.
+// It is intended for identifiers such as entity names, not for arbitrary prose.
+func pluralize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	lower := strings.ToLower(s)
+	if plural, ok := irregularPlurals[lower]; ok {
+		if unicode.IsUpper([]rune(s)[0]) {
+			return strings.ToUpper(plural[:1]) + plural[1:]
+		}
+		return plural
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return s[:len(s)-1] + "ies"
+	}
+
+	return s + "s"
+}
+
+func regexMatch(regex, s string) (bool, error) {
+	return regexp.MatchString(regex, s)
+}
+
+func regexFind(regex, s string) (string, error) {
+	r, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	return r.FindString(s), nil
+}
+
+func regexFindAll(regex string, n int, s string) ([]string, error) {
+	r, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	return r.FindAllString(s, n), nil
+}
+
+func regexReplaceAll(regex, s, repl string) (string, error) {
+	r, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	return r.ReplaceAllString(s, repl), nil
+}
+
+// empty reports whether the value is the zero value for its type, treating nil and empty collections as empty.
+func empty(given interface{}) bool {
+	if given == nil {
+		return true
+	}
+
+	val := reflect.ValueOf(given)
+	switch val.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.String:
+		return val.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return val.IsNil()
+	default:
+		return val.IsZero()
+	}
+}
+
+func defaultValue(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+func coalesce(values ...interface{}) interface{} {
+	for _, val := range values {
+		if !empty(val) {
+			return val
+		}
+	}
+	return nil
+}
+
+func ternary(truthy, falsy interface{}, condition bool) interface{} {
+	if condition {
+		return truthy
+	}
+	return falsy
+}
+
+// date formats the given time using a Go reference layout, accepting a time.Time or a unix timestamp.
+func date(layout string, when interface{}) (string, error) {
+	switch t := when.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		return t.Format(layout), nil
+	case int:
+		return time.Unix(int64(t), 0).Format(layout), nil
+	case int64:
+		return time.Unix(t, 0).Format(layout), nil
+	case float64:
+		return time.Unix(int64(t), 0).Format(layout), nil
+	default:
+		return "", fmt.Errorf("Unable to format value of type %T as a date", when)
+	}
+}
+
+// newUUID generates a random (version 4) UUID.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", errors.Wrap(err, "Error generating uuid")
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
+
+func sha256sum(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+func b64dec(s string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func toYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
@@ -0,0 +1,126 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaseConversionFunctions(t *testing.T) {
+	cases := map[string]string{
+		`{{ snakecase "myService" }}`:        "my_service",
+		`{{ snakecase "HTTPServer" }}`:       "http_server",
+		`{{ kebabcase "My Service" }}`:       "my-service",
+		`{{ kebabcase "MY_SERVICE" }}`:       "my-service",
+		`{{ camelcase "my-service" }}`:       "myService",
+		`{{ pascalcase "my-service" }}`:      "MyService",
+		`{{ pascalcase "user_api_key2" }}`:   "UserApiKey2",
+		`{{ "my-service" | pascalcase }}`:    "MyService",
+		`{{ title "hello world" }}`:          "Hello World",
+		`{{ upper "abc" }}{{ lower "DEF" }}`: "ABCdef",
+	}
+
+	for tmpl, expected := range cases {
+		result, err := defaultEngine.ParseAndExecutePath(tmpl, nil)
+		require.NoError(t, err, tmpl)
+		assert.Equal(t, expected, result, tmpl)
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	cases := map[string]string{
+		"user":    "users",
+		"box":     "boxes",
+		"branch":  "branches",
+		"company": "companies",
+		"day":     "days",
+		"child":   "children",
+		"Person":  "People",
+	}
+
+	for singular, plural := range cases {
+		assert.Equal(t, plural, pluralize(singular))
+	}
+}
+
+func TestStringFunctions(t *testing.T) {
+	cases := map[string]string{
+		`{{ trim "  padded  " }}`:                  "padded",
+		`{{ "foo.txt" | trimSuffix ".txt" }}`:      "foo",
+		`{{ "v1.2.3" | trimPrefix "v" }}`:          "1.2.3",
+		`{{ "a-b-c" | replace "-" "_" }}`:          "a_b_c",
+		`{{ "a,b" | split "," | join "|" }}`:       "a|b",
+		`{{ regexMatch "^[a-z]+$" "abc" }}`:        "true",
+		`{{ regexFind "[0-9]+" "abc123def" }}`:     "123",
+		`{{ regexReplaceAll "[^a-z]" "a1b2" "" }}`: "ab",
+		`{{ indent 2 "a\nb" }}`:                    "  a\n  b",
+		`{{ nindent 2 "a" }}`:                      "\n  a",
+		`{{ "" | default "fallback" }}`:            "fallback",
+		`{{ "set" | default "fallback" }}`:         "set",
+		`{{ coalesce "" "" "third" }}`:             "third",
+		`{{ "hello" | b64enc }}`:                   "aGVsbG8=",
+		`{{ "aGVsbG8=" | b64dec }}`:                "hello",
+		`{{ sha256sum "hello" }}`:                  "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		`{{ date "2006-01-02" 0 }}`:                "1970-01-01",
+		`{{ ternary "yes" "no" true }}`:            "yes",
+	}
+
+	for tmpl, expected := range cases {
+		result, err := defaultEngine.ParseAndExecutePath(tmpl, nil)
+		require.NoError(t, err, tmpl)
+		assert.Equal(t, expected, result, tmpl)
+	}
+}
+
+func TestUUIDIsRandomVersion4(t *testing.T) {
+	first, err := defaultEngine.ParseAndExecutePath("{{ uuid }}", nil)
+	require.NoError(t, err)
+	second, err := defaultEngine.ParseAndExecutePath("{{ uuid }}", nil)
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestSerialisationFunctions(t *testing.T) {
+	settings := map[string]interface{}{
+		"project": map[string]interface{}{"name": "foo"},
+	}
+
+	result, err := defaultEngine.ParseAndExecutePath("{{ toJson .project }}", settings)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"foo"}`, result)
+
+	result, err = defaultEngine.ParseAndExecutePath("{{ toYaml .project }}", settings)
+	require.NoError(t, err)
+	assert.Equal(t, "name: foo", result)
+}
+
+func TestFunctionsAreAvailableInFiles(t *testing.T) {
+	testFilePath := CreateTestTemplateFile(t, `{{ .ProjectName | kebabcase }}`)
+	defer os.RemoveAll(testFilePath)
+	var b bytes.Buffer
+
+	err := defaultEngine.ParseAndExecuteFile(testFilePath, validSettings, &b)
+	require.NoError(t, err)
+
+	assert.Equal(t, "foobar", b.String())
+}
@@ -0,0 +1,169 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+)
+
+// WithPartials loads every file under dir as a partial, available to all paths and files through {{ template "name" . }}.
+// A partial is named after its path inside dir without the extension, e.g. "license/header" for license/header.tmpl, and any
+// {{ define }} blocks inside it are shared too. Partials are parsed with the delimiters already set on the engine. A missing dir has no partials.
+func (e DefaultEngine) WithPartials(dir string) (DefaultEngine, error) {
+	partials := map[string]*parse.Tree{}
+	files := map[string]string{}
+
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && filePath == dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Error reading partials from %v", dir)
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("The partial '%v' is a symlink, partials can't be symlinks as they could read files from outside the template", rel)
+		}
+		name := strings.TrimSuffix(rel, path.Ext(rel))
+
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "Error reading partial '%v'", rel)
+		}
+		tmpl, err := template.New(name).Delims(e.leftDelim, e.rightDelim).Funcs(e.funcs).Parse(string(data))
+		if err != nil {
+			return errors.Wrapf(err, "Error parsing partial '%v'", rel)
+		}
+
+		for _, defined := range tmpl.Templates() {
+			if other, found := files[defined.Name()]; found {
+				return fmt.Errorf("The partial %q is defined in both '%v' and '%v'", defined.Name(), other, rel)
+			}
+			partials[defined.Name()] = defined.Tree
+			files[defined.Name()] = rel
+		}
+		return nil
+	})
+	if err != nil {
+		return e, err
+	}
+
+	e.partials = partials
+	e.partialFiles = files
+	return e, nil
+}
+
+// parse parses texts in order as the template called name, so later texts redefine the templates of earlier ones, adding the partials
+// to its set. Defining a template with the name of a partial, or calling a template that is neither a partial nor defined in texts,
+// is an error naming the line it happened on.
+func (e DefaultEngine) parse(name string, texts ...string) (*template.Template, error) {
+	tmpl := template.New(name).Delims(e.leftDelim, e.rightDelim).Funcs(e.funcs)
+	for _, text := range texts {
+		if _, err := tmpl.Parse(text); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, defined := range tmpl.Templates() {
+		if file, found := e.partialFiles[defined.Name()]; found && defined.Name() != name {
+			location, _ := defined.Tree.ErrorContext(defined.Tree.Root)
+			return nil, fmt.Errorf("template: %v: defines %q, which is already a partial in '%v'", location, defined.Name(), file)
+		}
+	}
+	for partial, tree := range e.partials {
+		if partial == name || tmpl.Lookup(partial) != nil {
+			continue
+		}
+		if _, err := tmpl.AddParseTree(partial, tree); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, defined := range tmpl.Templates() {
+		if defined.Tree == nil {
+			continue
+		}
+		if err := checkTemplateCalls(tmpl, defined.Tree, defined.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// checkTemplateCalls makes sure every {{ template }} action in the node calls a template in the set
+func checkTemplateCalls(set *template.Template, tree *parse.Tree, node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateCalls(set, tree, child); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkBranches(set, tree, n.List, n.ElseList)
+	case *parse.RangeNode:
+		return checkBranches(set, tree, n.List, n.ElseList)
+	case *parse.WithNode:
+		return checkBranches(set, tree, n.List, n.ElseList)
+	case *parse.TemplateNode:
+		if set.Lookup(n.Name) == nil {
+			line, _ := tree.ErrorContext(n)
+			return fmt.Errorf("template: %v: no partial or template named %q%v", line, n.Name, availablePartials(set))
+		}
+	}
+	return nil
+}
+
+func checkBranches(set *template.Template, tree *parse.Tree, list, elseList *parse.ListNode) error {
+	if err := checkTemplateCalls(set, tree, list); err != nil {
+		return err
+	}
+	return checkTemplateCalls(set, tree, elseList)
+}
+
+func availablePartials(set *template.Template) string {
+	var names []string
+	for _, defined := range set.Templates() {
+		if defined.Name() != set.Name() {
+			names = append(names, defined.Name())
+		}
+	}
+	if len(names) == 0 {
+		return ", there are no partials in .stencil/partials"
+	}
+	sort.Strings(names)
+	return fmt.Sprintf(", the available templates are: %v", strings.Join(names, ", "))
+}
@@ -0,0 +1,143 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesAndPathsCanUsePartials(t *testing.T) {
+	dir := createPartialsDir(t, map[string]string{
+		"header.tmpl":          "// Copyright {{ .ProjectName }}",
+		"go/helpers.tmpl":      `{{ define "shout" }}{{ . | upper }}!{{ end }}`,
+		"license/mit.txt.tmpl": "MIT",
+	})
+	defer os.RemoveAll(dir)
+
+	partialsEngine, err := defaultEngine.WithPartials(dir)
+	require.NoError(t, err)
+
+	testFilePath := CreateTestTemplateFile(t, "{{ template \"header\" . }}\n{{ template \"shout\" .Text }} {{ template \"license/mit.txt\" }}")
+	defer os.RemoveAll(testFilePath)
+	var b bytes.Buffer
+
+	err = partialsEngine.ParseAndExecuteFile(testFilePath, validSettings, &b)
+	require.NoError(t, err)
+	assert.Equal(t, "// Copyright Foobar\nHELLO WORLD! MIT", b.String())
+
+	executedPath, err := partialsEngine.ParseAndExecutePath(`{{ template "shout" .ProjectName }}.txt`, validSettings)
+	require.NoError(t, err)
+	assert.Equal(t, "FOOBAR!.txt", executedPath)
+}
+
+func TestWithPartialsIgnoresMissingDirectory(t *testing.T) {
+	partialsEngine, err := defaultEngine.WithPartials(filepath.Join(os.TempDir(), "stencil-no-partials-here"))
+	require.NoError(t, err)
+
+	executedPath, err := partialsEngine.ParseAndExecutePath(validPathTemplate, validSettings)
+	require.NoError(t, err)
+	assert.Equal(t, "Foobar.txt", executedPath)
+}
+
+func TestWithPartialsErrorsWhenPartialIsDefinedTwice(t *testing.T) {
+	dir := createPartialsDir(t, map[string]string{
+		"a.tmpl": `{{ define "footer" }}a{{ end }}`,
+		"b.tmpl": `{{ define "footer" }}b{{ end }}`,
+	})
+	defer os.RemoveAll(dir)
+
+	_, err := defaultEngine.WithPartials(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `The partial "footer" is defined in both 'a.tmpl' and 'b.tmpl'`)
+}
+
+func TestWithPartialsReportsParseErrors(t *testing.T) {
+	dir := createPartialsDir(t, map[string]string{"broken.tmpl": "line\n{{ if }}"})
+	defer os.RemoveAll(dir)
+
+	_, err := defaultEngine.WithPartials(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Error parsing partial 'broken.tmpl'")
+
+	templateErr, ok := ParseTemplateError(err)
+	require.True(t, ok)
+	assert.Equal(t, TemplateError{Name: "broken", Line: 2, Message: "missing value for if"}, templateErr)
+}
+
+func TestMissingPartialIsReportedWithItsLine(t *testing.T) {
+	dir := createPartialsDir(t, map[string]string{"header.tmpl": "header"})
+	defer os.RemoveAll(dir)
+	partialsEngine, err := defaultEngine.WithPartials(dir)
+	require.NoError(t, err)
+
+	testFilePath := CreateTestTemplateFile(t, "first\n{{ if .Text }}{{ template \"footer\" . }}{{ end }}")
+	defer os.RemoveAll(testFilePath)
+	var b bytes.Buffer
+
+	err = partialsEngine.ParseAndExecuteFile(testFilePath, validSettings, &b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no partial or template named "footer", the available templates are: header`)
+
+	templateErr, ok := ParseTemplateError(err)
+	require.True(t, ok)
+	assert.Equal(t, 2, templateErr.Line)
+	assert.Empty(t, b.String())
+}
+
+func TestFileCannotRedefineAPartial(t *testing.T) {
+	dir := createPartialsDir(t, map[string]string{"header.tmpl": "header"})
+	defer os.RemoveAll(dir)
+	partialsEngine, err := defaultEngine.WithPartials(dir)
+	require.NoError(t, err)
+
+	testFilePath := CreateTestTemplateFile(t, `{{ define "header" }}mine{{ end }}{{ template "header" }}`)
+	defer os.RemoveAll(testFilePath)
+	var b bytes.Buffer
+
+	err = partialsEngine.ParseAndExecuteFile(testFilePath, validSettings, &b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `defines "header", which is already a partial in 'header.tmpl'`)
+}
+
+func createPartialsDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "stencil-partials-")
+	require.NoError(t, err)
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	return dir
+}
+
+func TestWithPartialsRefusesSymlinks(t *testing.T) {
+	dir := createPartialsDir(t, map[string]string{"header.tmpl": "header"})
+	defer os.RemoveAll(dir)
+	if err := os.Symlink(filepath.Join(dir, "header.tmpl"), filepath.Join(dir, "leak.tmpl")); err != nil {
+		t.Skip("symlinks aren't supported")
+	}
+
+	_, err := defaultEngine.WithPartials(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "The partial 'leak.tmpl' is a symlink")
+}
@@ -0,0 +1,271 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+)
+
+// TokenEnv is always checked for a token to use with https templates, after any TokenEnv set in the AuthConfig
+const TokenEnv = "STENCIL_GIT_TOKEN"
+
+const defaultTokenUsername = "git"
+
+// AuthConfig describes how to authenticate with private git templates. It is read from the "auth" section of the user's config file.
+type AuthConfig struct {
+	// SSHKeyFile is a private key used for ssh templates. Without one the ssh agent is used, then the default keys in ~/.ssh.
+	SSHKeyFile string `mapstructure:"sshKeyFile"`
+	// SSHKeyPassphraseEnv names the environment variable holding the passphrase of SSHKeyFile
+	SSHKeyPassphraseEnv string `mapstructure:"sshKeyPassphraseEnv"`
+	// Username is sent along with a token for https templates, "git" by default
+	Username string `mapstructure:"username"`
+	// TokenEnv names an environment variable holding a token for https templates
+	TokenEnv string `mapstructure:"tokenEnv"`
+	// NetrcFile is the netrc file searched for https credentials, $NETRC or ~/.netrc by default
+	NetrcFile string `mapstructure:"netrcFile"`
+	// DisableNetrc stops credentials being read from a netrc file
+	DisableNetrc bool `mapstructure:"disableNetrc"`
+	// CredentialHelper asks git's configured credential helper for https credentials
+	CredentialHelper bool `mapstructure:"credentialHelper"`
+}
+
+// AuthFor chooses how to authenticate with the git repository at url. A nil AuthMethod means no credentials were found
+// and the repository is accessed anonymously, or with the credentials in the url itself.
+func (c AuthConfig) AuthFor(url string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	switch endpoint.Protocol {
+	case "ssh":
+		return c.sshAuth(endpoint)
+	case "http", "https":
+		return c.httpAuth(endpoint)
+	default:
+		return nil, nil
+	}
+}
+
+func (c AuthConfig) sshAuth(endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	user := endpoint.User
+	if user == "" {
+		user = "git"
+	}
+
+	if c.SSHKeyFile != "" {
+		keyFile, err := homedir.Expand(c.SSHKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		auth, err := ssh.NewPublicKeysFromFile(user, keyFile, c.envValue(c.SSHKeyPassphraseEnv))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error reading ssh key '%v'", keyFile)
+		}
+		return auth, nil
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		auth, err := ssh.NewSSHAgentAuth(user)
+		if err == nil {
+			return auth, nil
+		}
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, nil
+	}
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		if auth, err := ssh.NewPublicKeysFromFile(user, filepath.Join(home, ".ssh", name), ""); err == nil {
+			return auth, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (c AuthConfig) httpAuth(endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	if endpoint.Password != "" {
+		return nil, nil
+	}
+
+	username := c.Username
+	if username == "" {
+		username = endpoint.User
+	}
+	if username == "" {
+		username = defaultTokenUsername
+	}
+
+	for _, env := range []string{c.TokenEnv, TokenEnv} {
+		if token := c.envValue(env); token != "" {
+			return &http.BasicAuth{Username: username, Password: token}, nil
+		}
+	}
+
+	if !c.DisableNetrc {
+		login, password, err := c.netrcCredentials(endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+		if password != "" {
+			return &http.BasicAuth{Username: login, Password: password}, nil
+		}
+	}
+
+	if c.CredentialHelper {
+		if login, password := credentialHelper(endpoint); password != "" {
+			return &http.BasicAuth{Username: login, Password: password}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (c AuthConfig) envValue(name string) string {
+	if name == "" {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+func (c AuthConfig) netrcCredentials(host string) (string, string, error) {
+	path := c.NetrcFile
+	if path == "" {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", "", nil
+		}
+		path = filepath.Join(home, ".netrc")
+		if runtime.GOOS == "windows" {
+			path = filepath.Join(home, "_netrc")
+		}
+	}
+
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Error reading netrc file '%v'", path)
+	}
+
+	login, password := parseNetrc(string(data), host)
+	return login, password, nil
+}
+
+// parseNetrc finds the login and password for the host in the contents of a netrc file, falling back to the "default" entry
+func parseNetrc(contents, host string) (string, string) {
+	var login, password, defaultLogin, defaultPassword string
+	machine := ""
+	inMacro := false
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// Macro definitions run until a blank line
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			value := ""
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+
+			switch fields[i] {
+			case "machine":
+				machine = value
+				i++
+			case "default":
+				machine = "\x00default"
+			case "login":
+				if machine == host {
+					login = value
+				} else if machine == "\x00default" {
+					defaultLogin = value
+				}
+				i++
+			case "password":
+				if machine == host {
+					password = value
+				} else if machine == "\x00default" {
+					defaultPassword = value
+				}
+				i++
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+
+	if password != "" {
+		return login, password
+	}
+	return defaultLogin, defaultPassword
+}
+
+// credentialHelper asks "git credential fill" for the credentials of the endpoint, without letting git prompt on the terminal
+func credentialHelper(endpoint *transport.Endpoint) (string, string) {
+	input := "protocol=" + endpoint.Protocol + "\nhost=" + endpoint.Host + "\npath=" + strings.TrimPrefix(endpoint.Path, "/") + "\n\n"
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", ""
+	}
+
+	var username, password string
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		parts := strings.SplitN(string(line), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "username":
+			username = parts[1]
+		case "password":
+			password = parts[1]
+		}
+	}
+	return username, password
+}
@@ -0,0 +1,248 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/pkg/errors"
+)
+
+const (
+	cacheMetaFile     = "meta.json"
+	cacheTemplateDir  = "template"
+	cacheStagingDir   = "staging-"
+	defaultCacheAge   = time.Hour
+	cacheDirectoryEnv = "XDG_CACHE_HOME"
+)
+
+var commitHash = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// ErrNotCached is returned when running offline and the template hasn't been cached yet
+var ErrNotCached = errors.New("The template isn't in the cache, run once without --offline to fetch it")
+
+// Cache keeps cloned git templates between runs, keyed by url and ref
+type Cache struct {
+	// Dir is the directory holding the cached templates
+	Dir string
+	// MaxAge is how long a cached branch or tag is used before it is fetched again. Commits never go stale.
+	MaxAge time.Duration
+}
+
+// CacheEntry describes a template held in the cache
+type CacheEntry struct {
+	URL       string    `json:"url"`
+	Ref       string    `json:"ref"`
+	Commit    string    `json:"commit"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	// Dir is the directory the template was cloned into
+	Dir string `json:"-"`
+}
+
+// DefaultCacheDir returns the stencil directory inside $XDG_CACHE_HOME, or inside the user's cache directory if that isn't set
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv(cacheDirectoryEnv); dir != "" {
+		return filepath.Join(dir, "stencil"), nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "Error finding cache directory")
+	}
+	return filepath.Join(dir, "stencil"), nil
+}
+
+// NewCache creates a Cache in dir, using the default max age when maxAge is zero
+func NewCache(dir string, maxAge time.Duration) Cache {
+	if maxAge == 0 {
+		maxAge = defaultCacheAge
+	}
+	return Cache{Dir: dir, MaxAge: maxAge}
+}
+
+// Get returns the cached entry for the url and ref, if there is one
+func (c Cache) Get(url, ref string) (CacheEntry, bool) {
+	entry, err := readCacheEntry(filepath.Join(c.Dir, cacheKey(url, ref)))
+	if err != nil {
+		return CacheEntry{}, false
+	}
+	return entry, true
+}
+
+// Available reports whether the git template can be fetched for the url and ref, either from the cache, even when the cached copy is stale
+// as Fetch falls back to it, or from the remote. Only the cache is checked when offline.
+func (c Cache) Available(url, ref string, auth transport.AuthMethod, offline bool) bool {
+	if _, cached := c.Get(url, ref); cached {
+		return true
+	}
+	return !offline && IsGitURL(url, auth)
+}
+
+// IsFresh reports whether the entry can be used without fetching it again
+func (c Cache) IsFresh(entry CacheEntry) bool {
+	if commitHash.MatchString(entry.Ref) {
+		return true
+	}
+	return time.Since(entry.FetchedAt) < c.MaxAge
+}
+
+// Fetch returns the cached template for the url and ref, cloning it first if it is missing or stale.
+// When offline only the cache is used. If fetching a stale template fails, the stale copy is used instead.
+func (c Cache) Fetch(url string, opts PullOptions, offline, refresh bool) (CacheEntry, error) {
+	entry, cached := c.Get(url, opts.Ref)
+	if offline {
+		if !cached {
+			return CacheEntry{}, ErrNotCached
+		}
+		return entry, nil
+	}
+	if cached && !refresh && c.IsFresh(entry) {
+		return entry, nil
+	}
+
+	fetched, err := c.store(url, opts)
+	if err != nil {
+		if cached {
+			log.Printf("Unable to update template, using cached copy from %v: %v", entry.FetchedAt.Format(time.RFC1123), err)
+			return entry, nil
+		}
+		return CacheEntry{}, err
+	}
+	return fetched, nil
+}
+
+// List returns every entry in the cache, ordered by url and ref
+func (c Cache) List() ([]CacheEntry, error) {
+	dirs, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading cache directory")
+	}
+
+	var entries []CacheEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		if entry, err := readCacheEntry(filepath.Join(c.Dir, dir.Name())); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].Ref < entries[j].Ref
+	})
+	return entries, nil
+}
+
+// Remove deletes every cached ref of the url
+func (c Cache) Remove(url string) error {
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.URL == url {
+			if err := os.RemoveAll(filepath.Dir(entry.Dir)); err != nil {
+				return errors.Wrapf(err, "Error removing cached template %v", url)
+			}
+		}
+	}
+	return nil
+}
+
+// Clean deletes the whole cache
+func (c Cache) Clean() error {
+	if err := os.RemoveAll(c.Dir); err != nil {
+		return errors.Wrap(err, "Error removing cache directory")
+	}
+	return nil
+}
+
+// store clones the template into a staging directory inside the cache, then swaps it in place of any existing entry
+func (c Cache) store(url string, opts PullOptions) (CacheEntry, error) {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return CacheEntry{}, errors.Wrap(err, "Error creating cache directory")
+	}
+
+	staging, err := ioutil.TempDir(c.Dir, cacheStagingDir)
+	if err != nil {
+		return CacheEntry{}, errors.Wrap(err, "Error creating cache directory")
+	}
+	defer os.RemoveAll(staging)
+
+	commit, err := clone(filepath.Join(staging, cacheTemplateDir), url, opts)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	entry := CacheEntry{URL: url, Ref: opts.Ref, Commit: commit, FetchedAt: time.Now()}
+	meta, err := json.MarshalIndent(entry, "", "    ")
+	if err != nil {
+		return CacheEntry{}, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(staging, cacheMetaFile), meta, 0644); err != nil {
+		return CacheEntry{}, errors.Wrap(err, "Error writing cache entry")
+	}
+
+	entryDir := filepath.Join(c.Dir, cacheKey(url, opts.Ref))
+	if err = os.RemoveAll(entryDir); err != nil {
+		return CacheEntry{}, errors.Wrap(err, "Error replacing cache entry")
+	}
+	if err = os.Rename(staging, entryDir); err != nil {
+		return CacheEntry{}, errors.Wrap(err, "Error replacing cache entry")
+	}
+
+	entry.Dir = filepath.Join(entryDir, cacheTemplateDir)
+	return entry, nil
+}
+
+func readCacheEntry(entryDir string) (CacheEntry, error) {
+	var entry CacheEntry
+
+	data, err := ioutil.ReadFile(filepath.Join(entryDir, cacheMetaFile))
+	if err != nil {
+		return entry, err
+	}
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return entry, err
+	}
+
+	entry.Dir = filepath.Join(entryDir, cacheTemplateDir)
+	if !IsPath(entry.Dir) {
+		return entry, errors.New("Cache entry has no template")
+	}
+	return entry, nil
+}
+
+func cacheKey(url, ref string) string {
+	hash := sha256.Sum256([]byte(url + "\x00" + ref))
+	return hex.EncodeToString(hash[:])[:16]
+}
@@ -0,0 +1,194 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheFetchClonesAndReusesTemplate(t *testing.T) {
+	repo, commits := createTestRepo(t)
+	defer os.RemoveAll(repo)
+	cache := createTestCache(t, time.Hour)
+	defer cache.Clean()
+
+	entry, err := cache.Fetch(repo, PullOptions{Ref: "v1.0.0"}, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, commits["first"], entry.Commit)
+	assertFileContents(t, filepath.Join(entry.Dir, "version.txt"), "first")
+
+	// With the source gone, a fresh entry must still be served from the cache
+	require.NoError(t, os.RemoveAll(repo))
+
+	cached, err := cache.Fetch(repo, PullOptions{Ref: "v1.0.0"}, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, entry.Dir, cached.Dir)
+	assert.Equal(t, commits["first"], cached.Commit)
+}
+
+func TestCacheFetchUpdatesStaleTemplate(t *testing.T) {
+	repo, commits := createTestRepo(t)
+	defer os.RemoveAll(repo)
+	cache := createTestCache(t, time.Nanosecond)
+	defer cache.Clean()
+
+	entry, err := cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, commits["second"], entry.Commit)
+
+	third := commitToTestRepo(t, repo, "third")
+
+	entry, err = cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, third, entry.Commit)
+	assertFileContents(t, filepath.Join(entry.Dir, "version.txt"), "third")
+
+	entries, err := cache.List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestCacheFetchRefreshIgnoresFreshTemplate(t *testing.T) {
+	repo, _ := createTestRepo(t)
+	defer os.RemoveAll(repo)
+	cache := createTestCache(t, time.Hour)
+	defer cache.Clean()
+
+	_, err := cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+
+	third := commitToTestRepo(t, repo, "third")
+
+	entry, err := cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+	assert.NotEqual(t, third, entry.Commit)
+
+	entry, err = cache.Fetch(repo, PullOptions{}, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, third, entry.Commit)
+}
+
+func TestCacheFetchFallsBackToStaleTemplateWhenFetchFails(t *testing.T) {
+	repo, commits := createTestRepo(t)
+	defer os.RemoveAll(repo)
+	cache := createTestCache(t, time.Nanosecond)
+	defer cache.Clean()
+
+	_, err := cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(repo))
+
+	entry, err := cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, commits["second"], entry.Commit)
+}
+
+func TestCacheFetchOfflineOnlyUsesCache(t *testing.T) {
+	repo, commits := createTestRepo(t)
+	defer os.RemoveAll(repo)
+	cache := createTestCache(t, time.Nanosecond)
+	defer cache.Clean()
+
+	_, err := cache.Fetch(repo, PullOptions{}, true, false)
+	assert.Equal(t, ErrNotCached, err)
+
+	_, err = cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+
+	entry, err := cache.Fetch(repo, PullOptions{}, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, commits["second"], entry.Commit)
+}
+
+func TestAvailableAcceptsStaleCachedTemplateWhenRemoteIsGone(t *testing.T) {
+	repo, _ := createTestRepo(t)
+	defer os.RemoveAll(repo)
+	cache := createTestCache(t, time.Nanosecond)
+	defer cache.Clean()
+
+	assert.False(t, cache.Available(repo, "v1.0.0", nil, true))
+	assert.True(t, cache.Available(repo, "v1.0.0", nil, false))
+
+	_, err := cache.Fetch(repo, PullOptions{Ref: "v1.0.0"}, false, false)
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(repo))
+
+	assert.True(t, cache.Available(repo, "v1.0.0", nil, false))
+	assert.True(t, cache.Available(repo, "v1.0.0", nil, true))
+	assert.False(t, cache.Available(repo, "feature", nil, false))
+}
+
+func TestCommitsNeverGoStale(t *testing.T) {
+	cache := NewCache("", time.Nanosecond)
+
+	assert.True(t, cache.IsFresh(CacheEntry{Ref: "0298cc7176b3d1a907e6cada211a2c95c0b776c4", FetchedAt: time.Now().Add(-time.Hour)}))
+	assert.False(t, cache.IsFresh(CacheEntry{Ref: "main", FetchedAt: time.Now().Add(-time.Hour)}))
+}
+
+func TestCacheRemoveAndClean(t *testing.T) {
+	repo, _ := createTestRepo(t)
+	defer os.RemoveAll(repo)
+	cache := createTestCache(t, time.Hour)
+	defer cache.Clean()
+
+	_, err := cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+	_, err = cache.Fetch(repo, PullOptions{Ref: "feature"}, false, false)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Remove("some-other-url"))
+	entries, err := cache.List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	require.NoError(t, cache.Remove(repo))
+	entries, err = cache.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = cache.Fetch(repo, PullOptions{}, false, false)
+	require.NoError(t, err)
+	require.NoError(t, cache.Clean())
+	assert.False(t, IsPath(cache.Dir))
+}
+
+func TestDefaultCacheDirUsesXDGCacheHome(t *testing.T) {
+	original, set := os.LookupEnv("XDG_CACHE_HOME")
+	defer func() {
+		if set {
+			os.Setenv("XDG_CACHE_HOME", original)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+
+	os.Setenv("XDG_CACHE_HOME", filepath.Join("some", "cache"))
+	dir, err := DefaultCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("some", "cache", "stencil"), dir)
+}
+
+func createTestCache(t *testing.T, maxAge time.Duration) Cache {
+	dir, err := ioutil.TempDir("", "stencil-cache-")
+	require.NoError(t, err)
+	return NewCache(dir, maxAge)
+}
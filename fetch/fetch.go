@@ -16,14 +16,29 @@ package fetch
 
 import (
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/pkg/errors"
 )
 
+// PullOptions controls which version of a template is cloned
+type PullOptions struct {
+	// Ref is the branch, tag or commit to check out. The default branch is used when empty.
+	Ref string
+	// Shallow only fetches the commit being checked out rather than the whole history.
+	// It has no effect when Ref is a commit, as those can only be found in a full clone.
+	Shallow bool
+	// Auth authenticates with private repositories, see AuthConfig.AuthFor
+	Auth transport.AuthMethod
+}
+
 // IsPath is used to determin if the input string is a valid path on the local system
 func IsPath(input string) bool {
 	info, err := os.Stat(input)
@@ -35,40 +50,174 @@ func IsPath(input string) bool {
 	return true
 }
 
-// IsGitURL is used to determin if the input string is a valid Git Url
-func IsGitURL(input string) bool {
-	rem := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
-		Name: "origin",
-		URLs: []string{input},
-	})
-
-	_, err := rem.List(&git.ListOptions{}) // List tags to prove that git repo exists
+// IsGitURL is used to determin if the input string is a valid Git Url, using auth to access private repositories
+func IsGitURL(input string, auth transport.AuthMethod) bool {
+	_, err := listRemote(input, auth) // List tags to prove that git repo exists
 	return err == nil
 }
 
-// PullTemplate clones the template from its git repo
-func PullTemplate(repo string) (string, error) {
+// SplitRef separates a ref appended to a git url with an "@", e.g. "https://github.com/org/repo@v1.4.0".
+// The "@" of a user in the url, as in "git@github.com:org/repo.git", is not treated as a ref.
+func SplitRef(input string) (string, string) {
+	rest := input
+	if idx := strings.Index(rest, "://"); idx >= 0 {
+		rest = rest[idx+3:]
+
+		// Skip the user and password, e.g. "user:token@", which end at the last "@" before the path
+		authority := rest
+		if slash := strings.Index(rest, "/"); slash >= 0 {
+			authority = rest[:slash]
+		}
+		if at := strings.LastIndex(authority, "@"); at >= 0 {
+			rest = rest[at+1:]
+		}
+	}
+
+	// Skip past the user and host, which end at the first "/" or, for scp-like urls, the first ":"
+	hostEnd := strings.IndexAny(rest, "/:")
+	if hostEnd < 0 {
+		return input, ""
+	}
+	offset := len(input) - len(rest) + hostEnd
+
+	idx := strings.LastIndex(input[offset:], "@")
+	if idx < 0 {
+		return input, ""
+	}
+	idx += offset
+	return input[:idx], input[idx+1:]
+}
+
+// SplitSubdir separates a subdirectory appended to a git url with "//", e.g. "https://github.com/org/templates.git//go-service"
+func SplitSubdir(input string) (string, string) {
+	offset := 0
+	if idx := strings.Index(input, "://"); idx >= 0 {
+		offset = idx + 3
+	}
+
+	idx := strings.Index(input[offset:], "//")
+	if idx < 0 {
+		return input, ""
+	}
+	idx += offset
+	return input[:idx], strings.Trim(input[idx+2:], "/")
+}
+
+// ResolveSubdir returns the path of subdir within root, checking that it stays inside root and is a directory
+func ResolveSubdir(root, subdir string) (string, error) {
+	if subdir == "" {
+		return root, nil
+	}
+
+	cleaned := filepath.Clean(filepath.FromSlash(subdir))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("Subdirectory '%v' must be a relative path inside the template", subdir)
+	}
+
+	dir := filepath.Join(root, cleaned)
+	if !IsPath(dir) {
+		return "", errors.Errorf("Subdirectory '%v' doesn't exist in the template", subdir)
+	}
+	return dir, nil
+}
+
+// PullTemplate clones the template from its git repo, returning the directory it was cloned into and the hash of the commit checked out
+func PullTemplate(repo string, opts PullOptions) (string, string, error) {
 	dir, err := ioutil.TempDir("", "template-")
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	r, err := git.PlainClone(dir, false, &git.CloneOptions{
+	commit, err := clone(dir, repo, opts)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+
+	return dir, commit, nil
+}
+
+func clone(dir, repo string, opts PullOptions) (string, error) {
+	cloneOpts := &git.CloneOptions{
 		URL:               repo,
 		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
-	})
+		Auth:              opts.Auth,
+	}
+
+	revision := ""
+	if opts.Ref != "" {
+		refName, err := findRemoteRef(repo, opts.Ref, opts.Auth)
+		if err != nil {
+			return "", err
+		}
+		if refName != "" {
+			cloneOpts.ReferenceName = refName
+			cloneOpts.SingleBranch = true
+		} else {
+			// Not a branch or tag, so it must be a commit in the full history
+			revision = opts.Ref
+		}
+	}
+	if opts.Shallow && revision == "" {
+		cloneOpts.Depth = 1
+	}
+
+	r, err := git.PlainClone(dir, false, cloneOpts)
 	if err != nil {
-		os.RemoveAll(dir)
 		return "", err
 	}
 
+	if revision != "" {
+		hash, err := r.ResolveRevision(plumbing.Revision(revision))
+		if err != nil {
+			return "", errors.Errorf("Unable to find a branch, tag or commit named '%v'", revision)
+		}
+
+		w, err := r.Worktree()
+		if err != nil {
+			return "", err
+		}
+		if err = w.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+			return "", errors.Wrapf(err, "Error checking out commit %v", hash.String())
+		}
+		return hash.String(), nil
+	}
+
 	ref, err := r.Head()
 	if err != nil {
-		os.RemoveAll(dir)
 		return "", err
 	}
+	return ref.Hash().String(), nil
+}
 
-	log.Printf("Git repo cloned at hash %v", ref.Hash().String())
+// findRemoteRef returns the full name of the branch or tag called ref on the remote, or an empty name if there isn't one
+func findRemoteRef(repo, ref string, auth transport.AuthMethod) (plumbing.ReferenceName, error) {
+	refs, err := listRemote(repo, auth)
+	if err != nil {
+		return "", err
+	}
 
-	return dir, nil
+	candidates := []plumbing.ReferenceName{
+		plumbing.ReferenceName(ref),
+		plumbing.NewBranchReferenceName(ref),
+		plumbing.NewTagReferenceName(ref),
+	}
+	for _, candidate := range candidates {
+		for _, r := range refs {
+			if r.Name() == candidate && (candidate.IsBranch() || candidate.IsTag()) {
+				return candidate, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func listRemote(repo string, auth transport.AuthMethod) ([]*plumbing.Reference, error) {
+	rem := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repo},
+	})
+
+	return rem.List(&git.ListOptions{Auth: auth})
 }
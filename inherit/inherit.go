@@ -0,0 +1,211 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inherit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/pkg/errors"
+)
+
+// ConfigFile is the name of the file holding a template's values, inside its .stencil directory
+const ConfigFile = ".stencil.json"
+
+// Template is a template directory along with the files it replaced in the templates it extends
+type Template struct {
+	// Dir holds the files of the template and every template it extends
+	Dir string
+	// Bases maps each file that replaced a file of a base template to the file it replaced, see engine.DefaultEngine.WithBases
+	Bases map[string]string
+	// temp are the directories created while layering the templates
+	temp []string
+}
+
+// Local returns a template that doesn't extend anything
+func Local(dir string) Template {
+	return Template{Dir: dir}
+}
+
+// Layer creates a copy of the template in dir with the files of base underneath it, in a new temporary directory.
+// Files in both keep the template's version, with the base's recorded in Bases so its blocks can be overridden.
+// The base's values are merged with the template's .stencil.json, and its manifest with the template's using manifest.Extend.
+func Layer(base Template, dir string) (Template, error) {
+	layered, err := ioutil.TempDir("", "stencil-extends-")
+	if err != nil {
+		return Template{}, errors.Wrap(err, "Error creating directory for the extended template")
+	}
+	t := Template{Dir: layered, Bases: map[string]string{}, temp: append(base.temp, layered)}
+	for file, replaced := range base.Bases {
+		t.Bases[file] = replaced
+	}
+
+	err = t.copyLayer(base.Dir, func(rel, target string) {
+		if replaced, found := base.Bases[filepath.Join(base.Dir, rel)]; found {
+			t.Bases[target] = replaced
+		}
+	})
+	if err == nil {
+		err = t.copyLayer(dir, func(rel, target string) {
+			if _, err := os.Lstat(filepath.Join(base.Dir, rel)); err == nil {
+				t.Bases[target] = filepath.Join(base.Dir, rel)
+			} else {
+				delete(t.Bases, target)
+			}
+		})
+	}
+	if err == nil {
+		err = t.mergeValues(base.Dir, dir)
+	}
+	if err == nil {
+		err = t.mergeManifests(base.Dir, dir)
+	}
+	if err != nil {
+		t.Cleanup()
+		return Template{}, err
+	}
+	return t, nil
+}
+
+// Cleanup removes the directories created to layer the template
+func (t Template) Cleanup() {
+	for _, dir := range t.temp {
+		os.RemoveAll(dir)
+	}
+}
+
+// copyLayer copies the files of the template in src into the layered template, replacing any already there, and calls copied with
+// the relative and layered path of each file. Git metadata and the config and manifest, which are merged instead, are left out.
+func (t Template) copyLayer(src string, copied func(rel, target string)) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if info.Name() == ".git" {
+			return skip(info)
+		}
+		if rel == filepath.Join(".stencil", ConfigFile) || rel == filepath.Join(".stencil", manifest.FileName) {
+			return nil
+		}
+
+		target := filepath.Join(t.Dir, rel)
+		existing, statErr := os.Lstat(target)
+		if statErr == nil && existing.IsDir() != info.IsDir() {
+			return fmt.Errorf("'%v' is a directory in one template and a file in the template it extends, so they can't be layered", filepath.ToSlash(rel))
+		}
+
+		switch {
+		case info.IsDir():
+			if statErr == nil {
+				return nil
+			}
+			return os.Mkdir(target, info.Mode())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err = os.Symlink(link, target); err != nil {
+				return err
+			}
+		default:
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err = ioutil.WriteFile(target, data, info.Mode()); err != nil {
+				return err
+			}
+		}
+		copied(rel, target)
+		return nil
+	})
+}
+
+// mergeValues writes the values of the base with the template's values on top. Objects are merged, anything else is replaced.
+func (t Template) mergeValues(baseDir, dir string) error {
+	values := map[string]interface{}{}
+	for _, layer := range []string{baseDir, dir} {
+		data, err := ioutil.ReadFile(filepath.Join(layer, ".stencil", ConfigFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Error reading %v", ConfigFile)
+		}
+
+		var layerValues map[string]interface{}
+		if err = json.Unmarshal(data, &layerValues); err != nil {
+			return errors.Wrapf(err, "Error parsing '%v'", filepath.Join(layer, ".stencil", ConfigFile))
+		}
+		overlay(values, layerValues)
+	}
+
+	data, err := json.MarshalIndent(values, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Join(t.Dir, ".stencil"), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(t.Dir, ".stencil", ConfigFile), append(data, '\n'), 0644)
+}
+
+func (t Template) mergeManifests(baseDir, dir string) error {
+	base, err := manifest.Load(filepath.Join(baseDir, ".stencil"))
+	if err != nil {
+		return errors.Wrap(err, "Error parsing the manifest of the template it extends")
+	}
+	own, err := manifest.Load(filepath.Join(dir, ".stencil"))
+	if err != nil {
+		return errors.Wrap(err, "Error parsing manifest file")
+	}
+
+	merged, err := own.Extend(base)
+	if err != nil {
+		return err
+	}
+	return merged.Write(filepath.Join(t.Dir, ".stencil"))
+}
+
+// overlay copies values into target, merging objects found in both
+func overlay(target, values map[string]interface{}) {
+	for name, value := range values {
+		object, isObject := value.(map[string]interface{})
+		existing, hasObject := target[name].(map[string]interface{})
+		if isObject && hasObject {
+			overlay(existing, object)
+			continue
+		}
+		target[name] = value
+	}
+}
+
+func skip(info os.FileInfo) error {
+	if info.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inherit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayerPutsTheTemplatesFilesOverTheBase(t *testing.T) {
+	base := createTemplate(t, map[string]string{
+		".stencil/.stencil.json":        `{ "project": { "name": "base", "license": "MIT" }, "ci": true }`,
+		".stencil/manifest.json":        `{ "variables": [ { "name": "project.license" } ], "hooks": { "post": [ "git init" ] } }`,
+		".stencil/partials/header.tmpl": "header",
+		"Makefile":                      "base make",
+		"LICENSE":                       "base license",
+		".git/HEAD":                     "ref",
+	})
+	defer os.RemoveAll(base)
+	child := createTemplate(t, map[string]string{
+		".stencil/.stencil.json": `{ "project": { "name": "child" }, "module": "" }`,
+		".stencil/manifest.json": `{ "extends": "../base", "variables": [ { "name": "module" } ] }`,
+		"Makefile":               "child make",
+		"main.go":                "package main",
+	})
+	defer os.RemoveAll(child)
+
+	layered, err := Layer(Local(base), child)
+	require.NoError(t, err)
+	defer layered.Cleanup()
+
+	assert.Equal(t, "child make", readFile(t, layered.Dir, "Makefile"))
+	assert.Equal(t, "base license", readFile(t, layered.Dir, "LICENSE"))
+	assert.Equal(t, "package main", readFile(t, layered.Dir, "main.go"))
+	assert.Equal(t, "header", readFile(t, layered.Dir, ".stencil/partials/header.tmpl"))
+	assert.NoDirExists(t, filepath.Join(layered.Dir, ".git"))
+	assert.Equal(t, map[string]string{filepath.Join(layered.Dir, "Makefile"): filepath.Join(base, "Makefile")}, layered.Bases)
+
+	assert.JSONEq(t, `{ "project": { "name": "child", "license": "MIT" }, "ci": true, "module": "" }`, readFile(t, layered.Dir, ".stencil/.stencil.json"))
+	merged, err := manifest.Load(filepath.Join(layered.Dir, ".stencil"))
+	require.NoError(t, err)
+	assert.Equal(t, []manifest.Variable{{Name: "project.license"}, {Name: "module"}}, merged.Variables)
+	assert.Equal(t, []string{"git init"}, merged.Hooks.Post)
+	assert.Empty(t, merged.Extends)
+}
+
+func TestLayerKeepsTheBasesOfEveryLevel(t *testing.T) {
+	grandBase := createTemplate(t, map[string]string{"ci.yml": "grand", "Makefile": "grand"})
+	defer os.RemoveAll(grandBase)
+	base := createTemplate(t, map[string]string{"ci.yml": "base"})
+	defer os.RemoveAll(base)
+	child := createTemplate(t, map[string]string{"ci.yml": "child", ".stencil/.stencil.json": "{}"})
+	defer os.RemoveAll(child)
+
+	layeredBase, err := Layer(Local(grandBase), base)
+	require.NoError(t, err)
+	layered, err := Layer(layeredBase, child)
+	require.NoError(t, err)
+
+	childCI := filepath.Join(layered.Dir, "ci.yml")
+	baseCI := filepath.Join(layeredBase.Dir, "ci.yml")
+	assert.Equal(t, baseCI, layered.Bases[childCI])
+	assert.Equal(t, filepath.Join(grandBase, "ci.yml"), layered.Bases[baseCI])
+	assert.Equal(t, "grand", readFile(t, layered.Dir, "Makefile"))
+
+	layered.Cleanup()
+	assert.NoDirExists(t, layered.Dir)
+	assert.NoDirExists(t, layeredBase.Dir)
+}
+
+func TestLayerErrorsWhenAFileReplacesADirectory(t *testing.T) {
+	base := createTemplate(t, map[string]string{"docs/index.md": "docs"})
+	defer os.RemoveAll(base)
+	child := createTemplate(t, map[string]string{"docs": "not a directory"})
+	defer os.RemoveAll(child)
+
+	_, err := Layer(Local(base), child)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'docs' is a directory in one template and a file in the template it extends")
+}
+
+func TestLayerErrorsWhenDelimitersDiffer(t *testing.T) {
+	base := createTemplate(t, map[string]string{".stencil/manifest.json": `{ "delimiters": { "left": "[[", "right": "]]" } }`})
+	defer os.RemoveAll(base)
+	child := createTemplate(t, map[string]string{".stencil/.stencil.json": "{}"})
+	defer os.RemoveAll(child)
+
+	_, err := Layer(Local(base), child)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "they must be the same")
+}
+
+func createTemplate(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "stencil-inherit-")
+	require.NoError(t, err)
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	return dir
+}
+
+func readFile(t *testing.T, dir, rel string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+	require.NoError(t, err)
+	return string(data)
+}
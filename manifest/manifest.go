@@ -0,0 +1,306 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the name of the manifest file inside a template's .stencil directory
+const FileName = "manifest.json"
+
+// identifier matches names that can be used as a field in a template, e.g. {{ .entity }}
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Manifest describes how a template should be processed, alongside the values held in .stencil.json
+type Manifest struct {
+	Variables          []Variable          `json:"variables"`
+	Rules              []Rule              `json:"rules"`
+	Repeat             []Repeat            `json:"repeat"`
+	Hooks              Hooks               `json:"hooks"`
+	CopyOnly           []string            `json:"copyOnly"`
+	Delimiters         Delimiters          `json:"delimiters"`
+	DelimiterOverrides []DelimiterOverride `json:"delimiterOverrides"`
+	// UseGitignore leaves the paths matched by the template's own .gitignore files out of the generated project
+	UseGitignore bool `json:"useGitignore"`
+	// Extends is the local path or git url of a base template whose files are layered underneath this template's, see Extend
+	Extends string `json:"extends,omitempty"`
+}
+
+// Delimiters replace the default "{{" and "}}" template action delimiters. Empty delimiters keep the defaults.
+type Delimiters struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// DelimiterOverride sets the delimiters used for the contents of files matching Glob
+type DelimiterOverride struct {
+	Glob string `json:"glob"`
+	Delimiters
+}
+
+// Hooks are shell commands run before and after the template is processed, with the output directory as the working directory.
+// Each command is rendered as a template before it is run.
+type Hooks struct {
+	Pre  []string `json:"pre,omitempty"`
+	Post []string `json:"post,omitempty"`
+}
+
+// Empty reports whether there are no hooks to run
+func (h Hooks) Empty() bool {
+	return len(h.Pre) == 0 && len(h.Post) == 0
+}
+
+// Rule includes or excludes the template paths matching a glob depending on a condition.
+// Exactly one of Include or Exclude must be set. Include keeps matching paths only when the When template renders truthy,
+// Exclude drops matching paths when it does. An empty When is always true.
+type Rule struct {
+	Include string `json:"include,omitempty"`
+	Exclude string `json:"exclude,omitempty"`
+	When    string `json:"when,omitempty"`
+}
+
+// Repeat generates the template paths matching a glob once for every item of a list value. The item is available to the
+// paths' names, contents and rule conditions as the value named by As, or "item" if As is empty.
+type Repeat struct {
+	Glob    string `json:"glob"`
+	Foreach string `json:"foreach"`
+	As      string `json:"as,omitempty"`
+}
+
+// ItemName returns the name the current item is available as
+func (r Repeat) ItemName() string {
+	if r.As != "" {
+		return r.As
+	}
+	return "item"
+}
+
+// RepeatFor returns the first repeat whose glob matches the relative template path
+func (m Manifest) RepeatFor(relPath string) (Repeat, bool) {
+	for _, repeat := range m.Repeat {
+		if Match(repeat.Glob, relPath) {
+			return repeat, true
+		}
+	}
+	return Repeat{}, false
+}
+
+// Load reads the manifest from the template's .stencil directory. A template without a manifest gets an empty one.
+func Load(stencilDir string) (Manifest, error) {
+	var m Manifest
+
+	data, err := ioutil.ReadFile(filepath.Join(stencilDir, FileName))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("Error ocurred reading manifest file. Error: %v", err.Error())
+	}
+
+	if err = json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("Error ocurred parsing manifest file. Error: %v", err.Error())
+	}
+
+	if err = m.validate(); err != nil {
+		return m, fmt.Errorf("Invalid manifest file. Error: %v", err.Error())
+	}
+
+	return m, nil
+}
+
+// Extend merges the manifest of the base template it extends underneath this one. Variables declared by both use this manifest's
+// declaration, in the base's position. Rules, hooks and delimiter overrides of the base come first, so this manifest's run after them
+// and win, while its repeats come first so they are found before the base's. Both templates must use the same delimiters,
+// as their files are rendered together.
+func (m Manifest) Extend(base Manifest) (Manifest, error) {
+	if base.Delimiters.left() != m.Delimiters.left() || base.Delimiters.right() != m.Delimiters.right() {
+		return m, fmt.Errorf("The template uses the delimiters '%v %v' but the template it extends uses '%v %v', they must be the same",
+			m.Delimiters.left(), m.Delimiters.right(), base.Delimiters.left(), base.Delimiters.right())
+	}
+
+	merged := Manifest{
+		Delimiters:   m.Delimiters,
+		UseGitignore: m.UseGitignore || base.UseGitignore,
+	}
+	if merged.Delimiters.Left == "" {
+		merged.Delimiters = base.Delimiters
+	}
+
+	declared := map[string]Variable{}
+	for _, variable := range m.Variables {
+		declared[variable.Name] = variable
+	}
+	for _, variable := range base.Variables {
+		if own, found := declared[variable.Name]; found {
+			variable = own
+			delete(declared, variable.Name)
+		}
+		merged.Variables = append(merged.Variables, variable)
+	}
+	for _, variable := range m.Variables {
+		if _, found := declared[variable.Name]; found {
+			merged.Variables = append(merged.Variables, variable)
+		}
+	}
+
+	merged.Rules = append(append(merged.Rules, base.Rules...), m.Rules...)
+	merged.Repeat = append(append(merged.Repeat, m.Repeat...), base.Repeat...)
+	merged.Hooks.Pre = append(append(merged.Hooks.Pre, base.Hooks.Pre...), m.Hooks.Pre...)
+	merged.Hooks.Post = append(append(merged.Hooks.Post, base.Hooks.Post...), m.Hooks.Post...)
+	merged.CopyOnly = append(append(merged.CopyOnly, base.CopyOnly...), m.CopyOnly...)
+	merged.DelimiterOverrides = append(append(merged.DelimiterOverrides, base.DelimiterOverrides...), m.DelimiterOverrides...)
+
+	return merged, nil
+}
+
+// Write saves the manifest into the template's .stencil directory
+func (m Manifest) Write(stencilDir string) error {
+	data, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(stencilDir, FileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("Error ocurred writing manifest file. Error: %v", err.Error())
+	}
+	return nil
+}
+
+func (d Delimiters) left() string {
+	if d.Left == "" {
+		return "{{"
+	}
+	return d.Left
+}
+
+func (d Delimiters) right() string {
+	if d.Right == "" {
+		return "}}"
+	}
+	return d.Right
+}
+
+// Glob returns the pattern the rule applies to
+func (r Rule) Glob() string {
+	if r.Include != "" {
+		return r.Include
+	}
+	return r.Exclude
+}
+
+func (m Manifest) validate() error {
+	names := map[string]bool{}
+	for i, variable := range m.Variables {
+		if err := variable.validate(); err != nil {
+			return fmt.Errorf("variable %v %v", i+1, err.Error())
+		}
+		if names[variable.Name] {
+			return fmt.Errorf("variable '%v' is declared more than once", variable.Name)
+		}
+		names[variable.Name] = true
+	}
+	for i, rule := range m.Rules {
+		if (rule.Include == "") == (rule.Exclude == "") {
+			return fmt.Errorf("rule %v must set exactly one of 'include' or 'exclude'", i+1)
+		}
+		if _, err := path.Match(rule.Glob(), ""); err != nil {
+			return fmt.Errorf("rule %v has an invalid glob '%v'", i+1, rule.Glob())
+		}
+	}
+	for i, repeat := range m.Repeat {
+		if _, err := path.Match(repeat.Glob, ""); err != nil || repeat.Glob == "" {
+			return fmt.Errorf("repeat %v has an invalid glob '%v'", i+1, repeat.Glob)
+		}
+		if repeat.Foreach == "" {
+			return fmt.Errorf("repeat %v must set 'foreach' to the name of a list value", i+1)
+		}
+		if !identifier.MatchString(repeat.ItemName()) {
+			return fmt.Errorf("repeat %v has an invalid 'as' name '%v', it must be usable in a template like {{ .%v }}", i+1, repeat.As, repeat.As)
+		}
+	}
+	for _, glob := range m.CopyOnly {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("copyOnly has an invalid glob '%v'", glob)
+		}
+	}
+	if (m.Delimiters.Left == "") != (m.Delimiters.Right == "") {
+		return fmt.Errorf("delimiters must set both 'left' and 'right'")
+	}
+	for i, override := range m.DelimiterOverrides {
+		if _, err := path.Match(override.Glob, ""); err != nil || override.Glob == "" {
+			return fmt.Errorf("delimiter override %v has an invalid glob '%v'", i+1, override.Glob)
+		}
+		if override.Left == "" || override.Right == "" {
+			return fmt.Errorf("delimiter override %v must set both 'left' and 'right'", i+1)
+		}
+	}
+	return nil
+}
+
+// IsCopyOnly reports whether the relative template path should be copied verbatim instead of being rendered
+func (m Manifest) IsCopyOnly(relPath string) bool {
+	for _, glob := range m.CopyOnly {
+		if Match(glob, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the slash separated relative path matches the glob pattern.
+// A pattern without a slash matches the last element of the path at any depth, otherwise the pattern is anchored
+// to the template root and "**" matches any number of directories.
+func Match(pattern, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	pattern = strings.Trim(pattern, "/")
+
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		matched, _ := path.Match(pattern, path.Base(relPath))
+		return matched
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,224 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReturnsEmptyManifestWhenFileIsMissing(t *testing.T) {
+	dir := createStencilDir(t, "")
+	defer os.RemoveAll(dir)
+
+	m, err := Load(dir)
+	require.NoError(t, err)
+	assert.Empty(t, m.Rules)
+}
+
+func TestLoadReadsRules(t *testing.T) {
+	dir := createStencilDir(t, `{
+		"rules": [
+			{ "include": "**/Dockerfile", "when": "{{ .features.docker }}" },
+			{ "exclude": "docs" }
+		]
+	}`)
+	defer os.RemoveAll(dir)
+
+	m, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Include: "**/Dockerfile", When: "{{ .features.docker }}"},
+		{Exclude: "docs"},
+	}, m.Rules)
+}
+
+func TestLoadErrorsWhenRuleHasBothIncludeAndExclude(t *testing.T) {
+	dir := createStencilDir(t, `{ "rules": [ { "include": "a", "exclude": "b" } ] }`)
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rule 1")
+}
+
+func TestLoadReadsRepeats(t *testing.T) {
+	dir := createStencilDir(t, `{
+		"repeat": [
+			{ "glob": "handlers/{{ .entity }}.go", "foreach": "entities", "as": "entity" },
+			{ "glob": "migrations/*", "foreach": "db.tables" }
+		]
+	}`)
+	defer os.RemoveAll(dir)
+
+	m, err := Load(dir)
+	require.NoError(t, err)
+
+	repeat, ok := m.RepeatFor("handlers/{{ .entity }}.go")
+	require.True(t, ok)
+	assert.Equal(t, "entity", repeat.ItemName())
+
+	repeat, ok = m.RepeatFor("migrations/{{ .item }}.sql")
+	require.True(t, ok)
+	assert.Equal(t, Repeat{Glob: "migrations/*", Foreach: "db.tables"}, repeat)
+	assert.Equal(t, "item", repeat.ItemName())
+
+	_, ok = m.RepeatFor("main.go")
+	assert.False(t, ok)
+}
+
+func TestLoadErrorsWhenRepeatIsInvalid(t *testing.T) {
+	for manifest, message := range map[string]string{
+		`{ "repeat": [ { "glob": "a" } ] }`:                                  "must set 'foreach'",
+		`{ "repeat": [ { "foreach": "entities" } ] }`:                        "invalid glob",
+		`{ "repeat": [ { "glob": "a", "foreach": "b", "as": "my-item" } ] }`: "invalid 'as' name 'my-item'",
+	} {
+		dir := createStencilDir(t, manifest)
+		_, err := Load(dir)
+		os.RemoveAll(dir)
+
+		require.Error(t, err, manifest)
+		assert.Contains(t, err.Error(), message, manifest)
+	}
+}
+
+func TestLoadErrorsOnInvalidJson(t *testing.T) {
+	dir := createStencilDir(t, `{ "rules": `)
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir)
+	assert.Error(t, err)
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"Dockerfile", "Dockerfile", true},
+		{"Dockerfile", filepath.Join("app", "Dockerfile"), true},
+		{"*.md", filepath.Join("docs", "index.md"), true},
+		{"docs/*.md", filepath.Join("docs", "index.md"), true},
+		{"docs/*.md", filepath.Join("app", "docs", "index.md"), false},
+		{"**/docs/*.md", filepath.Join("app", "docs", "index.md"), true},
+		{"app/**", filepath.Join("app", "a", "b.go"), true},
+		{"app/**/b.go", filepath.Join("app", "b.go"), true},
+		{"/app", "app", true},
+		{"app/*.go", filepath.Join("app", "main.txt"), false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, Match(c.pattern, c.path), "%v against %v", c.pattern, c.path)
+	}
+}
+
+func createStencilDir(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "stencil-dir-")
+	require.NoError(t, err, "Unable to create temp dir for test")
+
+	if contents != "" {
+		err = ioutil.WriteFile(filepath.Join(dir, FileName), []byte(contents), 0644)
+		require.NoError(t, err, "Unable to create manifest for test")
+	}
+
+	return dir
+}
+
+func TestIsCopyOnly(t *testing.T) {
+	m := Manifest{CopyOnly: []string{"charts/**", "*.tpl"}}
+
+	assert.True(t, m.IsCopyOnly(filepath.Join("charts", "templates", "deployment.yaml")))
+	assert.True(t, m.IsCopyOnly(filepath.Join("src", "layout.tpl")))
+	assert.False(t, m.IsCopyOnly(filepath.Join("src", "main.go")))
+}
+
+func TestLoadReadsDelimiters(t *testing.T) {
+	dir := createStencilDir(t, `{
+		"delimiters": { "left": "[[", "right": "]]" },
+		"delimiterOverrides": [ { "glob": ".github/**", "left": "<%", "right": "%>" } ]
+	}`)
+	defer os.RemoveAll(dir)
+
+	m, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, Delimiters{Left: "[[", Right: "]]"}, m.Delimiters)
+	assert.Equal(t, []DelimiterOverride{{Glob: ".github/**", Delimiters: Delimiters{Left: "<%", Right: "%>"}}}, m.DelimiterOverrides)
+}
+
+func TestLoadErrorsWhenDelimiterOverrideIsIncomplete(t *testing.T) {
+	dir := createStencilDir(t, `{ "delimiterOverrides": [ { "glob": "*.yaml", "left": "[[" } ] }`)
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "delimiter override 1")
+}
+
+func TestExtendMergesBaseManifestUnderneath(t *testing.T) {
+	base := Manifest{
+		Variables:  []Variable{{Name: "project.name"}, {Name: "license", Default: "MIT"}},
+		Rules:      []Rule{{Exclude: "ci", When: "{{ .noCI }}"}},
+		Repeat:     []Repeat{{Glob: "*.md", Foreach: "docs"}},
+		Hooks:      Hooks{Pre: []string{"base pre"}, Post: []string{"base post"}},
+		CopyOnly:   []string{"*.png"},
+		Delimiters: Delimiters{Left: "{{", Right: "}}"},
+	}
+	child := Manifest{
+		Variables: []Variable{{Name: "license", Default: "Apache-2.0"}, {Name: "module"}},
+		Rules:     []Rule{{Include: "go.mod"}},
+		Repeat:    []Repeat{{Glob: "cmd/*", Foreach: "commands"}},
+		Hooks:     Hooks{Post: []string{"go mod tidy"}},
+		Extends:   "../base",
+	}
+
+	merged, err := child.Extend(base)
+	require.NoError(t, err)
+	assert.Equal(t, []Variable{{Name: "project.name"}, {Name: "license", Default: "Apache-2.0"}, {Name: "module"}}, merged.Variables)
+	assert.Equal(t, []Rule{{Exclude: "ci", When: "{{ .noCI }}"}, {Include: "go.mod"}}, merged.Rules)
+	assert.Equal(t, []Repeat{{Glob: "cmd/*", Foreach: "commands"}, {Glob: "*.md", Foreach: "docs"}}, merged.Repeat)
+	assert.Equal(t, Hooks{Pre: []string{"base pre"}, Post: []string{"base post", "go mod tidy"}}, merged.Hooks)
+	assert.Equal(t, []string{"*.png"}, merged.CopyOnly)
+	assert.Equal(t, Delimiters{Left: "{{", Right: "}}"}, merged.Delimiters)
+	assert.Empty(t, merged.Extends)
+}
+
+func TestExtendErrorsWhenDelimitersDiffer(t *testing.T) {
+	child := Manifest{Delimiters: Delimiters{Left: "[[", Right: "]]"}}
+
+	_, err := child.Extend(Manifest{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'[[ ]]' but the template it extends uses '{{ }}'")
+}
+
+func TestWriteCanBeLoadedAgain(t *testing.T) {
+	dir := createStencilDir(t, "")
+	defer os.RemoveAll(dir)
+	m := Manifest{
+		Variables:          []Variable{{Name: "enabled", Type: TypeBool, Default: false}},
+		Rules:              []Rule{{Include: "docs", When: "{{ .enabled }}"}},
+		DelimiterOverrides: []DelimiterOverride{{Glob: "*.yaml", Delimiters: Delimiters{Left: "<%", Right: "%>"}}},
+	}
+
+	require.NoError(t, m.Write(dir))
+	loaded, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, m, loaded)
+}
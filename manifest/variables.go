@@ -0,0 +1,164 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// The types a Variable can declare. A Variable without a type takes the type of its value in .stencil.json.
+const (
+	TypeString = "string"
+	TypeBool   = "bool"
+	TypeNumber = "number"
+	TypeList   = "list"
+)
+
+// Variable describes a template value and how the user should be asked for it. Variables are asked for in the order they are declared.
+type Variable struct {
+	// Name is the dotted name of the value, e.g. "project.name"
+	Name string `json:"name"`
+	// Label is shown when asking for the value, in place of the name
+	Label string `json:"label,omitempty"`
+	// Help is a longer description shown before asking for the value
+	Help string `json:"help,omitempty"`
+	// Type is one of TypeString, TypeBool, TypeNumber or TypeList
+	Type string `json:"type,omitempty"`
+	// Choices are the only values allowed. Every item of a list must be one of the choices.
+	Choices []interface{} `json:"choices,omitempty"`
+	// Regex must match the whole of a string value, or every item of a list
+	Regex string `json:"regex,omitempty"`
+	// Required values can't be left empty
+	Required bool `json:"required,omitempty"`
+	// Default is used when the value isn't in .stencil.json and wasn't provided. A string default is rendered as a template,
+	// so it can refer to the values of earlier variables.
+	Default interface{} `json:"default,omitempty"`
+}
+
+// Prompt returns the text used to ask for the variable
+func (v Variable) Prompt() string {
+	if v.Label != "" {
+		return v.Label
+	}
+	return v.Name
+}
+
+// Like returns a value with the variable's type, for converting user input. Variables without a type use the type of current,
+// or of their choices, falling back to a string.
+func (v Variable) Like(current interface{}) interface{} {
+	switch v.Type {
+	case TypeString:
+		return ""
+	case TypeBool:
+		return false
+	case TypeNumber:
+		return float64(0)
+	case TypeList:
+		if list, ok := current.([]interface{}); ok {
+			return list
+		}
+		return []interface{}{}
+	}
+
+	if current != nil {
+		return current
+	}
+	if len(v.Choices) > 0 {
+		return v.Choices[0]
+	}
+	return ""
+}
+
+// Validate checks the value against the variable's required flag, choices and regex
+func (v Variable) Validate(value interface{}) error {
+	items, isList := value.([]interface{})
+	if !isList {
+		items = []interface{}{value}
+	}
+
+	if isEmpty(value) {
+		if v.Required {
+			return fmt.Errorf("a value is required")
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		if len(v.Choices) > 0 && !v.isChoice(item) {
+			return fmt.Errorf("'%v' isn't one of %v", item, v.ChoicesText())
+		}
+		if v.Regex != "" {
+			text, ok := item.(string)
+			if !ok || !regexp.MustCompile(anchored(v.Regex)).MatchString(text) {
+				return fmt.Errorf("'%v' doesn't match the pattern '%v'", item, v.Regex)
+			}
+		}
+	}
+	return nil
+}
+
+// ChoicesText lists the choices for showing to the user
+func (v Variable) ChoicesText() string {
+	choices := make([]string, len(v.Choices))
+	for i, choice := range v.Choices {
+		choices[i] = fmt.Sprint(choice)
+	}
+	return strings.Join(choices, ", ")
+}
+
+func (v Variable) isChoice(value interface{}) bool {
+	for _, choice := range v.Choices {
+		if reflect.DeepEqual(choice, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v Variable) validate() error {
+	if v.Name == "" {
+		return fmt.Errorf("must have a name")
+	}
+	switch v.Type {
+	case "", TypeString, TypeBool, TypeNumber, TypeList:
+	default:
+		return fmt.Errorf("has an unknown type '%v'", v.Type)
+	}
+	if v.Regex != "" {
+		if _, err := regexp.Compile(anchored(v.Regex)); err != nil {
+			return fmt.Errorf("has an invalid regex '%v'", v.Regex)
+		}
+	}
+	return nil
+}
+
+func anchored(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
+
+func isEmpty(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(typed) == ""
+	case []interface{}:
+		return len(typed) == 0
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReadsVariables(t *testing.T) {
+	dir := createStencilDir(t, `{
+		"variables": [
+			{ "name": "project.name", "label": "Project name", "help": "Used for the binary", "required": true, "regex": "[a-z-]+" },
+			{ "name": "license", "choices": ["MIT", "Apache-2.0"], "default": "MIT" }
+		]
+	}`)
+	defer os.RemoveAll(dir)
+
+	m, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Variable{
+		{Name: "project.name", Label: "Project name", Help: "Used for the binary", Required: true, Regex: "[a-z-]+"},
+		{Name: "license", Choices: []interface{}{"MIT", "Apache-2.0"}, Default: "MIT"},
+	}, m.Variables)
+}
+
+func TestLoadErrorsOnInvalidVariables(t *testing.T) {
+	cases := map[string]string{
+		`{ "variables": [ { "label": "No name" } ] }`:           "variable 1 must have a name",
+		`{ "variables": [ { "name": "a", "type": "date" } ] }`:  "unknown type 'date'",
+		`{ "variables": [ { "name": "a", "regex": "(" } ] }`:    "invalid regex",
+		`{ "variables": [ { "name": "a" }, { "name": "a" } ] }`: "declared more than once",
+	}
+
+	for contents, expected := range cases {
+		dir := createStencilDir(t, contents)
+		_, err := Load(dir)
+		os.RemoveAll(dir)
+
+		if assert.Error(t, err, contents) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+}
+
+func TestVariableValidate(t *testing.T) {
+	cases := []struct {
+		variable Variable
+		value    interface{}
+		valid    bool
+	}{
+		{Variable{Required: true}, "", false},
+		{Variable{Required: true}, []interface{}{}, false},
+		{Variable{Required: true}, "value", true},
+		{Variable{Regex: "[a-z]+"}, "", true},
+		{Variable{Regex: "[a-z]+"}, "abc", true},
+		{Variable{Regex: "[a-z]+"}, "abc1", false},
+		{Variable{Regex: "[a-z]+"}, []interface{}{"a", "b"}, true},
+		{Variable{Regex: "[a-z]+"}, []interface{}{"a", "B"}, false},
+		{Variable{Choices: []interface{}{"MIT", 2.0}}, "MIT", true},
+		{Variable{Choices: []interface{}{"MIT", 2.0}}, 2.0, true},
+		{Variable{Choices: []interface{}{"MIT", 2.0}}, "GPL", false},
+		{Variable{Choices: []interface{}{"a", "b"}}, []interface{}{"a", "c"}, false},
+	}
+
+	for _, c := range cases {
+		err := c.variable.Validate(c.value)
+		assert.Equal(t, c.valid, err == nil, "%+v with %#v", c.variable, c.value)
+	}
+}
+
+func TestVariableLike(t *testing.T) {
+	assert.Equal(t, false, Variable{Type: TypeBool}.Like("text"))
+	assert.Equal(t, float64(0), Variable{Type: TypeNumber}.Like(nil))
+	assert.Equal(t, []interface{}{"a"}, Variable{Type: TypeList}.Like([]interface{}{"a"}))
+	assert.Equal(t, true, Variable{}.Like(true))
+	assert.Equal(t, 1.0, Variable{Choices: []interface{}{1.0, 2.0}}.Like(nil))
+	assert.Equal(t, "", Variable{}.Like(nil))
+}
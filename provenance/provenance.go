@@ -0,0 +1,80 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Chris-Greaves/stencil/confighelper"
+)
+
+// FileName is the name of the provenance file written into the root of a generated project
+const FileName = ".stencil-provenance.json"
+
+// Provenance records which template, and which answers, a project was generated from, so it can be updated later
+type Provenance struct {
+	// Template is the git url or local path of the template, without any ref or subdirectory
+	Template string `json:"template"`
+	// Ref is the branch, tag or commit asked for, empty for the default branch
+	Ref string `json:"ref,omitempty"`
+	// Commit is the commit of a git template that was used
+	Commit string `json:"commit,omitempty"`
+	// Subdir is the subdirectory of the repository or path holding the template
+	Subdir string `json:"subdir,omitempty"`
+	// Bases is the commit used of each git template extended, by the source in the manifest that extends it
+	Bases          map[string]string      `json:"bases,omitempty"`
+	StencilVersion string                 `json:"stencilVersion"`
+	Values         map[string]interface{} `json:"values"`
+}
+
+// Load reads the provenance file from the root of a generated project
+func Load(projectDir string) (Provenance, error) {
+	var p Provenance
+
+	data, err := ioutil.ReadFile(filepath.Join(projectDir, FileName))
+	if os.IsNotExist(err) {
+		return p, fmt.Errorf("'%v' has no %v, only projects generated by stencil can be updated", projectDir, FileName)
+	}
+	if err != nil {
+		return p, fmt.Errorf("Error ocurred reading provenance file. Error: %v", err.Error())
+	}
+
+	if err = json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("Error ocurred parsing provenance file. Error: %v", err.Error())
+	}
+	return p, nil
+}
+
+// Write saves the provenance file into the root of the project
+func (p Provenance) Write(projectDir string) error {
+	data, err := json.MarshalIndent(p, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(projectDir, FileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("Error ocurred writing provenance file. Error: %v", err.Error())
+	}
+	return nil
+}
+
+// Settings returns the recorded answers as Settings, ready to be applied to the template's configuration
+func (p Provenance) Settings() []confighelper.Setting {
+	return confighelper.FlattenValues(p.Values)
+}
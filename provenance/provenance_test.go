@@ -0,0 +1,60 @@
+// Copyright © 2018 Christopher Greaves <cjgreaves97@hotmail.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provenance
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Chris-Greaves/stencil/confighelper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndLoadRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stencil-provenance-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	written := Provenance{
+		Template:       "https://github.com/acme/templates.git",
+		Ref:            "v2",
+		Commit:         "3f2c1a9d5e8b7c6a4f3e2d1c0b9a8f7e6d5c4b3a",
+		Subdir:         "go-service",
+		StencilVersion: "v1.2.0",
+		Values:         map[string]interface{}{"project": map[string]interface{}{"name": "svc"}, "docker": true},
+	}
+	require.NoError(t, written.Write(dir))
+
+	loaded, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, written, loaded)
+
+	assert.Equal(t, []confighelper.Setting{
+		{Name: "docker", Value: true},
+		{Name: "project.name", Value: "svc"},
+	}, loaded.Settings())
+}
+
+func TestLoadErrorsWhenProjectHasNoProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stencil-provenance-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only projects generated by stencil can be updated")
+}